@@ -6,12 +6,26 @@ import (
 	"net/http"
 
 	"github.com/avagenc/zee-api/internal/account"
+	"github.com/avagenc/zee-api/internal/apikey"
+	"github.com/avagenc/zee-api/internal/audit"
+	"github.com/avagenc/zee-api/internal/command"
 	"github.com/avagenc/zee-api/internal/config"
 	"github.com/avagenc/zee-api/internal/device"
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/internal/energy"
+	"github.com/avagenc/zee-api/internal/group"
+	"github.com/avagenc/zee-api/internal/idempotency"
+	"github.com/avagenc/zee-api/internal/infrared"
+	"github.com/avagenc/zee-api/internal/jwt"
 	"github.com/avagenc/zee-api/internal/middleware"
 	"github.com/avagenc/zee-api/internal/postgres"
+	"github.com/avagenc/zee-api/internal/secretbox"
+	"github.com/avagenc/zee-api/internal/sharing"
 	"github.com/avagenc/zee-api/internal/system"
+	"github.com/avagenc/zee-api/internal/tenant"
 	"github.com/avagenc/zee-api/internal/tuya"
+	"github.com/avagenc/zee-api/internal/tuyatoken"
+	"github.com/avagenc/zee-api/pkg/api"
 	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
 )
@@ -38,65 +52,245 @@ func main() {
 		log.Fatalf("FATAL: Schema validation failed: %v", err)
 	}
 
+	keyring, err := secretbox.LoadKeyring(cfg.Security.SecretKey, cfg.Security.SecretKeyID, cfg.Security.SecretKeyFile, cfg.Security.SecretIndexKey)
+	if err != nil {
+		log.Fatalf("FATAL: Failed to load secret keys: %v", err)
+	}
+	secretBox, err := secretbox.NewBox(keyring)
+	if err != nil {
+		log.Fatalf("FATAL: Failed to create secret box: %v", err)
+	}
+	if !secretBox.Enabled() {
+		log.Println("Warning: no secret key configured; Tuya UIDs are stored unencrypted and tenants cannot be created")
+	}
+
 	repo := struct {
-		account account.Repository
+		account     account.Repository
+		apiKey      apikey.Repository
+		audit       audit.Repository
+		command     command.Repository
+		energy      energy.Repository
+		group       group.Repository
+		sharing     sharing.Repository
+		tenant      tenant.Repository
+		idempotency middleware.IdempotencyStore
 	}{
-		account: account.NewRepository(pgPool),
+		account:     account.NewRepository(pgPool, secretBox),
+		apiKey:      apikey.NewRepository(pgPool),
+		audit:       audit.NewRepository(pgPool),
+		command:     command.NewRepository(pgPool),
+		energy:      energy.NewRepository(pgPool),
+		group:       group.NewRepository(pgPool),
+		idempotency: idempotency.NewRepository(pgPool),
+		sharing:     sharing.NewRepository(pgPool),
+		tenant:      tenant.NewRepository(pgPool, secretBox),
+	}
+
+	var tokenStore tuya.TokenStore = tuya.NewMemoryTokenStore()
+	if cfg.Tuya.TokenStore == config.TuyaTokenStorePostgres {
+		tokenStore = tuyatoken.NewRepository(pgPool, secretBox)
 	}
 
 	tuyaClient, err := tuya.NewClient(
 		cfg.Tuya.AccessID,
 		cfg.Tuya.AccessSecret,
 		cfg.Tuya.BaseURL,
+		tokenStore,
 	)
 	if err != nil {
 		log.Fatalf("FATAL: Failed to create Tuya client: %v", err)
 	}
 
+	var tuyaRegistry *tuya.Registry
+	tenantSvc := tenant.NewService(repo.tenant, func(tenantID string) { tuyaRegistry.Invalidate(tenantID) })
+	tuyaRegistry = tuya.NewRegistry(tuyaClient, tenantSvc.Credentials, func(ctx context.Context) (string, string) {
+		return api.GetTenantIDFromContext(ctx), api.GetRegionFromContext(ctx)
+	})
+
 	tuyaIoTClient := struct {
-		device device.TuyaIoTClient
+		device   device.TuyaIoTClient
+		energy   energy.TuyaIoTClient
+		infrared infrared.TuyaIoTClient
 	}{
-		device: device.NewTuyaIoTClient(tuyaClient),
+		device:   device.NewTuyaIoTClient(tuyaRegistry),
+		energy:   energy.NewTuyaIoTClient(tuyaRegistry),
+		infrared: infrared.NewTuyaIoTClient(tuyaRegistry),
 	}
 
 	accountSvc := account.NewService(repo.account)
+	apiKeySvc := apikey.NewService(repo.apiKey, cfg.Security.APIKey)
+	auditSvc := audit.NewService(repo.audit)
+	commandSvc := command.NewService(repo.command)
+	energySvc := energy.NewService(accountSvc.ListLinked, tuyaIoTClient.device.List, tuyaIoTClient.energy, repo.energy)
+	sharingSvc := sharing.NewService(repo.sharing, accountSvc.ListLinked, tuyaIoTClient.device.List)
+	deviceSvc := device.NewService(accountSvc.ListLinked, tuyaIoTClient.device, energySvc.RecordStatus, commandSvc, auditSvc.RecordCommand, sharingSvc.ListReceived)
 
 	svc := struct {
-		account account.Service
-		device  device.Service
+		account  account.Service
+		apiKey   apikey.Service
+		audit    audit.Service
+		command  command.Service
+		device   device.Service
+		energy   energy.Service
+		group    group.Service
+		infrared infrared.Service
+		sharing  sharing.Service
+		tenant   tenant.Service
 	}{
-		account: accountSvc,
-		device:  device.NewService(accountSvc.GetTuyaUID, tuyaIoTClient.device),
+		account:  accountSvc,
+		apiKey:   apiKeySvc,
+		audit:    auditSvc,
+		command:  commandSvc,
+		device:   deviceSvc,
+		energy:   energySvc,
+		group:    group.NewService(repo.group, accountSvc.ListLinked, tuyaIoTClient.device, auditSvc.RecordCommand),
+		infrared: infrared.NewService(deviceSvc.VerifyAccess, tuyaIoTClient.infrared, auditSvc.RecordCommand),
+		sharing:  sharingSvc,
+		tenant:   tenantSvc,
 	}
 
 	hdl := struct {
-		system  *system.Handler
-		account *account.Handler
-		device  *device.Handler
+		system   *system.Handler
+		account  *account.Handler
+		apiKey   *apikey.Handler
+		audit    *audit.Handler
+		command  *command.Handler
+		device   *device.Handler
+		energy   *energy.Handler
+		group    *group.Handler
+		infrared *infrared.Handler
+		sharing  *sharing.Handler
+		tenant   *tenant.Handler
 	}{
-		system:  system.NewHandler(cfg.App.Name, cfg.App.Version, cfg.App.Env),
-		account: account.NewHandler(svc.account),
-		device:  device.NewHandler(svc.device),
+		system:   system.NewHandler(cfg.App.Name, cfg.App.Version, cfg.App.Env),
+		account:  account.NewHandler(svc.account),
+		apiKey:   apikey.NewHandler(svc.apiKey),
+		audit:    audit.NewHandler(svc.audit),
+		command:  command.NewHandler(svc.command),
+		device:   device.NewHandler(svc.device),
+		energy:   energy.NewHandler(svc.energy),
+		group:    group.NewHandler(svc.group),
+		infrared: infrared.NewHandler(svc.infrared),
+		sharing:  sharing.NewHandler(svc.sharing),
+		tenant:   tenant.NewHandler(svc.tenant),
+	}
+
+	go command.NewWorker(repo.command, tuyaIoTClient.device, auditSvc.RecordCommand).Run(context.Background())
+	go energy.NewSampler(accountSvc.ListAll, tuyaIoTClient.device.List, energySvc.RecordStatus).Run(context.Background())
+
+	requireUser := middleware.RequireUserIdentity
+	if cfg.Auth.Mode == config.AuthModeHeader {
+		log.Printf("Warning: AUTH_MODE=%s trusts the x-user-id header; only API keys with the %s scope can call user routes. Use %s or %s for end-user clients",
+			config.AuthModeHeader, domain.ScopeImpersonate, config.AuthModeJWT, config.AuthModeHybrid)
+	} else {
+		verifier, err := jwt.NewVerifier(jwt.Options{
+			JWKSFile:      cfg.Auth.JWTJWKSFile,
+			PublicKeyFile: cfg.Auth.JWTPublicKeyFile,
+			HMACSecret:    cfg.Auth.JWTHMACSecret,
+			Issuer:        cfg.Auth.JWTIssuer,
+			Audience:      cfg.Auth.JWTAudience,
+			UserClaim:     cfg.Auth.JWTUserClaim,
+			Leeway:        cfg.Auth.JWTLeeway,
+		})
+		if err != nil {
+			log.Fatalf("FATAL: Failed to create JWT verifier: %v", err)
+		}
+		requireUser = middleware.RequireBearerIdentity(verifier, cfg.Auth.Mode == config.AuthModeHybrid)
 	}
 
+	idempotent := middleware.Idempotency(repo.idempotency, cfg.Security.IdempotencyTTL)
+
+	readDevices := middleware.RequireScope(domain.ScopeDevicesRead)
+	sendCommands := middleware.RequireScope(domain.ScopeCommandsSend)
+
+	readLimiter := middleware.NewRateLimiter("read",
+		middleware.RateLimit{Requests: cfg.RateLimit.ReadsPerKey, Window: cfg.RateLimit.Window},
+		middleware.RateLimit{Requests: cfg.RateLimit.ReadsPerUser, Window: cfg.RateLimit.Window},
+	)
+	commandLimiter := middleware.NewRateLimiter("command",
+		middleware.RateLimit{Requests: cfg.RateLimit.CommandsPerKey, Window: cfg.RateLimit.Window},
+		middleware.RateLimit{Requests: cfg.RateLimit.CommandsPerUser, Window: cfg.RateLimit.Window},
+	)
+
 	r := chi.NewRouter()
 
 	r.Use(chiMiddleware.RequestID)
 	r.Use(chiMiddleware.RealIP)
 	r.Use(chiMiddleware.Logger)
 	r.Use(chiMiddleware.Recoverer)
-	r.Use(middleware.AuthenticateAPIKey(cfg.Security.APIKey))
+	r.Use(middleware.AuthenticateAPIKey(apiKeySvc))
 
 	r.Get("/", hdl.system.Index)
 
 	r.Group(func(r chi.Router) {
-		r.Use(middleware.RequireUserIdentity)
+		r.Use(middleware.RequireScope(domain.ScopeAdmin))
+
+		r.Get("/audit/commands", hdl.audit.ListCommands)
+
+		r.Get("/admin/api-keys", hdl.apiKey.List)
+		r.Post("/admin/api-keys", hdl.apiKey.Mint)
+		r.Post("/admin/api-keys/{keyId}/rotate", hdl.apiKey.Rotate)
+		r.Delete("/admin/api-keys/{keyId}", hdl.apiKey.Revoke)
+
+		r.Post("/admin/accounts", hdl.account.Link)
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequirePlatformKey)
+
+			r.Get("/admin/tenants", hdl.tenant.List)
+			r.Post("/admin/tenants", hdl.tenant.Create)
+			r.Put("/admin/tenants/{tenantId}/credentials", hdl.tenant.UpdateCredentials)
+			r.Delete("/admin/tenants/{tenantId}", hdl.tenant.Disable)
+		})
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(requireUser)
+
+		r.Group(func(r chi.Router) {
+			r.Use(readDevices)
+			r.Use(readLimiter.Limit)
+
+			r.Get("/account", hdl.account.Get)
+			r.Get("/accounts", hdl.account.List)
+			r.Get("/devices", hdl.device.List)
+			r.Get("/devices/{deviceId}", hdl.device.Get)
+			r.Get("/devices/{deviceId}/logs", hdl.device.GetLogs)
+			r.Get("/devices/{deviceId}/remotes", hdl.infrared.ListRemotes)
+			r.Get("/devices/{deviceId}/remotes/{remoteId}/keys", hdl.infrared.ListKeys)
+			r.Get("/devices/{deviceId}/remotes/{remoteId}/ac", hdl.infrared.GetACState)
+			r.Get("/groups", hdl.group.List)
+			r.Get("/groups/{groupId}", hdl.group.Get)
+			r.Get("/commands/{commandId}", hdl.command.Get)
+			r.Get("/energy", hdl.energy.Summary)
+			r.Get("/energy/devices/{deviceId}", hdl.energy.GetDevice)
+			r.Get("/shares", hdl.sharing.ListGranted)
+			r.Get("/shares/received", hdl.sharing.ListReceived)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(sendCommands)
+			r.Use(commandLimiter.Limit)
+
+			r.With(idempotent).Post("/devices/commands", hdl.device.SendBatchCommands)
+			r.With(idempotent).Post("/devices/{deviceId}/commands", hdl.device.SendCommands)
+			r.With(idempotent).Post("/devices/{deviceId}/capabilities", hdl.device.SendCapabilities)
+			r.With(idempotent).Post("/devices/{deviceId}/light", hdl.device.SendLight)
+			r.With(idempotent).Post("/devices/{deviceId}/remotes/{remoteId}/keys", hdl.infrared.SendKey)
+			r.With(idempotent).Put("/devices/{deviceId}/remotes/{remoteId}/ac", hdl.infrared.SetACState)
+			r.Patch("/devices/{deviceId}", hdl.device.Rename)
+			r.Put("/devices/{deviceId}/channels/{identifier}", hdl.device.RenameChannel)
+
+			r.Post("/groups", hdl.group.Create)
+			r.Put("/groups/{groupId}", hdl.group.Update)
+			r.Delete("/groups/{groupId}", hdl.group.Delete)
+			r.With(idempotent).Post("/groups/{groupId}/commands", hdl.group.SendCommands)
 
-		r.Get("/account", hdl.account.Get)
-		r.Get("/devices", hdl.device.List)
+			r.Patch("/accounts/{accountId}", hdl.account.Update)
+			r.Delete("/accounts/{accountId}", hdl.account.Unlink)
 
-		r.Route("/devices/{deviceId}", func(r chi.Router) {
-			r.Post("/commands", hdl.device.SendCommands)
+			r.Post("/shares", hdl.sharing.Grant)
+			r.Delete("/shares/{shareId}", hdl.sharing.Revoke)
 		})
 	})
 
@@ -0,0 +1,56 @@
+// Command mintkey mints an API key directly against the database. Use it to
+// create the first admin key, since the deprecated shared API_KEY cannot
+// manage keys. The plaintext key is printed once and cannot be retrieved
+// again.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/avagenc/zee-api/internal/apikey"
+	"github.com/avagenc/zee-api/internal/config"
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/internal/postgres"
+)
+
+func main() {
+	name := flag.String("name", "admin", "name of the key")
+	scopes := flag.String("scopes", domain.ScopeAdmin, "comma-separated scopes")
+	tenantID := flag.String("tenant", "", "tenant to bind the key to; empty for a platform key")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("FATAL: %v", err)
+	}
+
+	pgPool, err := postgres.NewPool(
+		cfg.Database.URL,
+		cfg.Database.MaxConns,
+		cfg.Database.MinConns,
+		cfg.Database.MaxConnLifetime,
+		cfg.Database.MaxConnIdleTime,
+	)
+	if err != nil {
+		log.Fatalf("FATAL: Failed to connect to database: %v", err)
+	}
+	defer pgPool.Close()
+
+	ctx := context.Background()
+	if err := postgres.ValidateSchema(ctx, pgPool); err != nil {
+		log.Fatalf("FATAL: Schema validation failed: %v", err)
+	}
+
+	svc := apikey.NewService(apikey.NewRepository(pgPool), "")
+	key, raw, err := svc.Mint(ctx, *name, strings.Split(*scopes, ","), *tenantID, nil)
+	if err != nil {
+		log.Fatalf("FATAL: Failed to mint API key: %v", err)
+	}
+
+	log.Printf("Minted API key %s (%s) with scopes %s", key.ID, key.Name, strings.Join(key.Scopes, ","))
+	fmt.Println(raw)
+}
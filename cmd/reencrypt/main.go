@@ -0,0 +1,72 @@
+// Command reencrypt moves every secret zee-api stores in Postgres to the
+// active key of the configured keyring. Run it after making a new key active
+// and before removing the old one from the keyring; it is safe to re-run.
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/avagenc/zee-api/internal/account"
+	"github.com/avagenc/zee-api/internal/config"
+	"github.com/avagenc/zee-api/internal/postgres"
+	"github.com/avagenc/zee-api/internal/secretbox"
+	"github.com/avagenc/zee-api/internal/tenant"
+)
+
+type reencrypter interface {
+	Reencrypt(ctx context.Context) (int, error)
+}
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("FATAL: %v", err)
+	}
+
+	keyring, err := secretbox.LoadKeyring(cfg.Security.SecretKey, cfg.Security.SecretKeyID, cfg.Security.SecretKeyFile, cfg.Security.SecretIndexKey)
+	if err != nil {
+		log.Fatalf("FATAL: Failed to load secret keys: %v", err)
+	}
+	secretBox, err := secretbox.NewBox(keyring)
+	if err != nil {
+		log.Fatalf("FATAL: Failed to create secret box: %v", err)
+	}
+	if !secretBox.Enabled() {
+		log.Fatalf("FATAL: No secret key configured")
+	}
+
+	pgPool, err := postgres.NewPool(
+		cfg.Database.URL,
+		cfg.Database.MaxConns,
+		cfg.Database.MinConns,
+		cfg.Database.MaxConnLifetime,
+		cfg.Database.MaxConnIdleTime,
+	)
+	if err != nil {
+		log.Fatalf("FATAL: Failed to connect to database: %v", err)
+	}
+	defer pgPool.Close()
+
+	ctx := context.Background()
+	if err := postgres.ValidateSchema(ctx, pgPool); err != nil {
+		log.Fatalf("FATAL: Schema validation failed: %v", err)
+	}
+
+	targets := []struct {
+		name string
+		repo reencrypter
+	}{
+		{name: "tenant credentials", repo: tenant.NewRepository(pgPool, secretBox)},
+		{name: "Tuya accounts", repo: account.NewRepository(pgPool, secretBox)},
+	}
+
+	log.Printf("Re-encrypting secrets under key %q", secretBox.ActiveKeyID())
+	for _, target := range targets {
+		n, err := target.repo.Reencrypt(ctx)
+		if err != nil {
+			log.Fatalf("FATAL: Failed to re-encrypt %s after %d rows: %v", target.name, n, err)
+		}
+		log.Printf("Re-encrypted %d %s", n, target.name)
+	}
+}
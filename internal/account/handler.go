@@ -2,13 +2,24 @@ package account
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 
+	"github.com/avagenc/zee-api/internal/domain"
 	"github.com/avagenc/zee-api/pkg/api"
+	"github.com/go-chi/chi/v5"
 )
 
+const maxLabelLength = 50
+
 type Service interface {
-	Get(ctx context.Context, ownerID string) (Account, error)
+	Get(ctx context.Context, ownerID string) (domain.TuyaAccount, error)
+	List(ctx context.Context, ownerID string) ([]domain.TuyaAccount, error)
+	Link(ctx context.Context, acc domain.TuyaAccount) (domain.TuyaAccount, error)
+	Update(ctx context.Context, ownerID, accountID string, update domain.TuyaAccountUpdate) (domain.TuyaAccount, error)
+	Unlink(ctx context.Context, ownerID, accountID string) error
 }
 
 type Handler struct {
@@ -33,9 +44,152 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	}
 
 	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Account retrieved", map[string]any{
+		"id":        acc.ID,
 		"ownerId":   acc.OwnerID,
 		"tuyaUid":   acc.TuyaUID,
+		"label":     acc.Label,
+		"region":    acc.Region,
 		"createdAt": acc.CreatedAt,
 		"updatedAt": acc.UpdatedAt,
 	}, nil))
 }
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	ownerID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	accounts, err := h.svc.List(r.Context(), ownerID)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Accounts retrieved successfully", accounts, nil))
+}
+
+// Link attaches a Tuya account to a user. It is admin-only because the API
+// cannot prove the caller controls the Tuya UID being linked.
+func (h *Handler) Link(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		OwnerID  string `json:"owner_id"`
+		TuyaUID  string `json:"tuya_uid"`
+		Label    string `json:"label"`
+		Region   string `json:"region"`
+		TenantID string `json:"tenant_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid request body", nil))
+		return
+	}
+
+	req.OwnerID = strings.TrimSpace(req.OwnerID)
+	req.TuyaUID = strings.TrimSpace(req.TuyaUID)
+	if req.OwnerID == "" || req.TuyaUID == "" {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "owner_id and tuya_uid are required", nil))
+		return
+	}
+
+	label, ok := validLabel(w, req.Label)
+	if !ok {
+		return
+	}
+
+	acc, err := h.svc.Link(r.Context(), domain.TuyaAccount{
+		OwnerID:  req.OwnerID,
+		TenantID: strings.TrimSpace(req.TenantID),
+		TuyaUID:  req.TuyaUID,
+		Region:   strings.ToLower(strings.TrimSpace(req.Region)),
+		Label:    label,
+	})
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusCreated, api.NewSuccessResponse("Account linked successfully", acc, nil))
+}
+
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	ownerID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	var req struct {
+		Label     string `json:"label"`
+		Region    string `json:"region"`
+		IsDefault bool   `json:"is_default"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid request body", nil))
+		return
+	}
+
+	label, ok := validLabel(w, req.Label)
+	if !ok {
+		return
+	}
+
+	update := domain.TuyaAccountUpdate{
+		Label:       label,
+		Region:      strings.ToLower(strings.TrimSpace(req.Region)),
+		MakeDefault: req.IsDefault,
+	}
+	if update == (domain.TuyaAccountUpdate{}) {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Nothing to update", nil))
+		return
+	}
+
+	acc, err := h.svc.Update(r.Context(), ownerID, chi.URLParam(r, "accountId"), update)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Account updated successfully", acc, nil))
+}
+
+func (h *Handler) Unlink(w http.ResponseWriter, r *http.Request) {
+	ownerID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	if err := h.svc.Unlink(r.Context(), ownerID, chi.URLParam(r, "accountId")); err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Account unlinked successfully", nil, nil))
+}
+
+func validLabel(w http.ResponseWriter, label string) (string, bool) {
+	label = strings.TrimSpace(label)
+	if len(label) > maxLabelLength {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Label cannot exceed 50 characters", nil))
+		return "", false
+	}
+	return label, true
+}
+
+func respondError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrTuyaAccountNotFound):
+		api.Respond(w, http.StatusNotFound, api.NewErrorResponse("NOT_FOUND", "Tuya account not found", nil))
+	case errors.Is(err, domain.ErrTenantNotFound):
+		api.Respond(w, http.StatusNotFound, api.NewErrorResponse("NOT_FOUND", "Tenant not found", nil))
+	case errors.Is(err, domain.ErrInvalidRegion):
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", err.Error(), nil))
+	case errors.Is(err, domain.ErrTuyaAccountConflict):
+		api.Respond(w, http.StatusConflict, api.NewErrorResponse("CONFLICT", err.Error(), nil))
+	default:
+		api.Respond(w, http.StatusInternalServerError, api.NewErrorResponse("INTERNAL_ERROR", err.Error(), nil))
+	}
+}
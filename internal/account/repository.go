@@ -3,53 +3,377 @@ package account
 import (
 	"context"
 	"errors"
-	"time"
+	"fmt"
 
+	"github.com/avagenc/zee-api/internal/domain"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-type Account struct {
-	OwnerID   string
-	TuyaUID   string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+const (
+	invalidTextRepresentation = "22P02"
+	foreignKeyViolation       = "23503"
+	uniqueViolation           = "23505"
+)
+
+const accountColumns = `id, owner_id, COALESCE(tenant_id::text, ''), COALESCE(tuya_uid, ''), tuya_uid_sealed, COALESCE(region, ''), label, is_default, created_at, updated_at`
+
+const reencryptBatchSize = 100
+
+// Cipher encrypts Tuya UIDs at rest. Index gives the keyed hash that backs the
+// uniqueness constraint on the encrypted column.
+type Cipher interface {
+	Enabled() bool
+	Seal(plaintext []byte) ([]byte, error)
+	Open(sealed []byte) ([]byte, error)
+	Rewrap(sealed []byte) ([]byte, bool, error)
+	Index(value []byte) (string, error)
 }
 
 type repository struct {
-	pool *pgxpool.Pool
+	pool   *pgxpool.Pool
+	cipher Cipher
+}
+
+// NewRepository stores Tuya UIDs encrypted when the cipher has a key and in
+// plaintext otherwise; the re-encryption command seals plaintext rows once a
+// key is configured.
+func NewRepository(pool *pgxpool.Pool, cipher Cipher) *repository {
+	return &repository{pool: pool, cipher: cipher}
+}
+
+// tenantScope matches rows of the tenant in the given placeholder, where an
+// empty tenant ID selects the deployment's default project.
+const tenantScope = `tenant_id IS NOT DISTINCT FROM NULLIF(%s, '')::uuid`
+
+func (r *repository) GetDefault(ctx context.Context, tenantID, ownerID string) (domain.TuyaAccount, error) {
+	query := `SELECT ` + accountColumns + ` FROM tuya_app_accounts WHERE ` + fmt.Sprintf(tenantScope, "$1") + ` AND owner_id = $2 AND is_default AND deleted_at IS NULL`
+
+	acc, err := r.scanAccount(r.pool.QueryRow(ctx, query, tenantID, ownerID))
+	if err != nil {
+		if isNotFound(err) {
+			return domain.TuyaAccount{}, ErrNotLinked
+		}
+		return domain.TuyaAccount{}, err
+	}
+	return acc, nil
 }
 
-func NewRepository(pool *pgxpool.Pool) *repository {
-	return &repository{pool: pool}
+func (r *repository) List(ctx context.Context, tenantID, ownerID string) ([]domain.TuyaAccount, error) {
+	query := `SELECT ` + accountColumns + ` FROM tuya_app_accounts WHERE ` + fmt.Sprintf(tenantScope, "$1") + ` AND owner_id = $2 AND deleted_at IS NULL ORDER BY is_default DESC, created_at`
+
+	rows, err := r.pool.Query(ctx, query, tenantID, ownerID)
+	if err != nil {
+		if isNotFound(err) {
+			return []domain.TuyaAccount{}, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []domain.TuyaAccount{}
+	for rows.Next() {
+		acc, err := r.scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, acc)
+	}
+	return accounts, rows.Err()
 }
 
-func (r *repository) GetTuyaUID(ctx context.Context, ownerID string) (string, error) {
-	var tuyaUID string
-	query := `SELECT tuya_uid FROM tuya_app_accounts WHERE owner_id = $1 AND deleted_at IS NULL`
+// ListAll returns every active account across tenants, for background jobs
+// that act on behalf of all users.
+func (r *repository) ListAll(ctx context.Context) ([]domain.TuyaAccount, error) {
+	query := `SELECT ` + accountColumns + ` FROM tuya_app_accounts WHERE deleted_at IS NULL ORDER BY created_at`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []domain.TuyaAccount{}
+	for rows.Next() {
+		acc, err := r.scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, acc)
+	}
+	return accounts, rows.Err()
+}
 
-	err := r.pool.QueryRow(ctx, query, ownerID).Scan(&tuyaUID)
+// Create links a new account. The owner's first active account becomes the
+// default.
+func (r *repository) Create(ctx context.Context, acc domain.TuyaAccount) (domain.TuyaAccount, error) {
+	plainUID, sealedUID, uidIndex, err := r.protectUID(acc.TuyaUID)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return "", ErrNotLinked
+		return domain.TuyaAccount{}, err
+	}
+
+	query := `
+		INSERT INTO tuya_app_accounts (tenant_id, owner_id, tuya_uid, tuya_uid_sealed, tuya_uid_index, region, label, is_default)
+		SELECT NULLIF($1, '')::uuid, $2, NULLIF($3, ''), $4, NULLIF($5, ''), NULLIF($7, ''), $6, NOT EXISTS (
+			SELECT 1 FROM tuya_app_accounts WHERE ` + fmt.Sprintf(tenantScope, "$1") + ` AND owner_id = $2 AND deleted_at IS NULL
+		)
+		RETURNING ` + accountColumns
+
+	created, err := r.scanAccount(r.pool.QueryRow(ctx, query, acc.TenantID, acc.OwnerID, plainUID, sealedUID, uidIndex, acc.Label, acc.Region))
+	if err != nil {
+		if isNotFound(err) || isForeignKeyViolation(err) {
+			return domain.TuyaAccount{}, domain.ErrTenantNotFound
+		}
+		if isConflict(err) {
+			return domain.TuyaAccount{}, domain.ErrTuyaAccountConflict
+		}
+		return domain.TuyaAccount{}, err
+	}
+	return created, nil
+}
+
+func (r *repository) Update(ctx context.Context, tenantID, ownerID, accountID string, update domain.TuyaAccountUpdate) (domain.TuyaAccount, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return domain.TuyaAccount{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if update.MakeDefault {
+		unset := `UPDATE tuya_app_accounts SET is_default = FALSE, updated_at = NOW() WHERE ` + fmt.Sprintf(tenantScope, "$1") + ` AND owner_id = $2 AND id <> $3 AND is_default AND deleted_at IS NULL`
+		if _, err := tx.Exec(ctx, unset, tenantID, ownerID, accountID); err != nil {
+			if isNotFound(err) {
+				return domain.TuyaAccount{}, domain.ErrTuyaAccountNotFound
+			}
+			return domain.TuyaAccount{}, err
+		}
+	}
+
+	query := `
+		UPDATE tuya_app_accounts
+		SET label = COALESCE(NULLIF($4, ''), label), region = COALESCE(NULLIF($5, ''), region), is_default = is_default OR $6, updated_at = NOW()
+		WHERE ` + fmt.Sprintf(tenantScope, "$1") + ` AND owner_id = $2 AND id = $3 AND deleted_at IS NULL
+		RETURNING ` + accountColumns
+
+	acc, err := r.scanAccount(tx.QueryRow(ctx, query, tenantID, ownerID, accountID, update.Label, update.Region, update.MakeDefault))
+	if err != nil {
+		switch {
+		case isNotFound(err):
+			return domain.TuyaAccount{}, domain.ErrTuyaAccountNotFound
+		case isConflict(err):
+			return domain.TuyaAccount{}, domain.ErrTuyaAccountConflict
+		}
+		return domain.TuyaAccount{}, err
+	}
+
+	return acc, tx.Commit(ctx)
+}
+
+// Delete unlinks an account. When the default is removed the oldest remaining
+// account is promoted so the owner always has a default while linked.
+func (r *repository) Delete(ctx context.Context, tenantID, ownerID, accountID string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var wasDefault bool
+	query := `
+		WITH target AS (
+			SELECT id, is_default FROM tuya_app_accounts
+			WHERE ` + fmt.Sprintf(tenantScope, "$1") + ` AND owner_id = $2 AND id = $3 AND deleted_at IS NULL
+			FOR UPDATE
+		)
+		UPDATE tuya_app_accounts a SET deleted_at = NOW(), is_default = FALSE, updated_at = NOW()
+		FROM target
+		WHERE a.id = target.id
+		RETURNING target.is_default`
+
+	if err := tx.QueryRow(ctx, query, tenantID, ownerID, accountID).Scan(&wasDefault); err != nil {
+		if isNotFound(err) {
+			return domain.ErrTuyaAccountNotFound
+		}
+		return err
+	}
+
+	if wasDefault {
+		promote := `
+			UPDATE tuya_app_accounts SET is_default = TRUE, updated_at = NOW()
+			WHERE id = (
+				SELECT id FROM tuya_app_accounts
+				WHERE ` + fmt.Sprintf(tenantScope, "$1") + ` AND owner_id = $2 AND deleted_at IS NULL
+				ORDER BY created_at
+				LIMIT 1
+			)`
+		if _, err := tx.Exec(ctx, promote, tenantID, ownerID); err != nil {
+			return err
 		}
-		return "", err
 	}
 
-	return tuyaUID, nil
+	return tx.Commit(ctx)
+}
+
+// Reencrypt seals plaintext Tuya UIDs and moves sealed ones to the cipher's
+// active key, recomputing their indexes, and returns how many rows were
+// rewritten. Unlinked accounts are included.
+func (r *repository) Reencrypt(ctx context.Context) (int, error) {
+	if !r.cipher.Enabled() {
+		return 0, errors.New("cannot re-encrypt Tuya accounts without a secret key")
+	}
+
+	var rewritten int
+	var lastID string
+
+	for {
+		n, last, err := r.reencryptBatch(ctx, lastID)
+		if err != nil {
+			return rewritten, err
+		}
+		rewritten += n
+		if last == "" {
+			return rewritten, nil
+		}
+		lastID = last
+	}
 }
 
-func (r *repository) Get(ctx context.Context, ownerID string) (Account, error) {
-	var acc Account
-	query := `SELECT owner_id, tuya_uid, created_at, updated_at FROM tuya_app_accounts WHERE owner_id = $1 AND deleted_at IS NULL`
+// reencryptBatch rewrites one batch of accounts after afterID and returns the
+// last ID it visited, or "" when there are no more rows.
+func (r *repository) reencryptBatch(ctx context.Context, afterID string) (int, string, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT id, COALESCE(tuya_uid, ''), tuya_uid_sealed, COALESCE(tuya_uid_index, '') FROM tuya_app_accounts
+		WHERE id > COALESCE(NULLIF($1, '')::uuid, '00000000-0000-0000-0000-000000000000')
+		ORDER BY id
+		LIMIT $2
+		FOR UPDATE`
 
-	err := r.pool.QueryRow(ctx, query, ownerID).Scan(&acc.OwnerID, &acc.TuyaUID, &acc.CreatedAt, &acc.UpdatedAt)
+	rows, err := tx.Query(ctx, query, afterID, reencryptBatchSize)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return Account{}, errors.New("tuya account not found")
+		return 0, "", err
+	}
+
+	type row struct {
+		id       string
+		plainUID string
+		sealed   []byte
+		index    string
+	}
+	var batch []row
+	for rows.Next() {
+		var rw row
+		if err := rows.Scan(&rw.id, &rw.plainUID, &rw.sealed, &rw.index); err != nil {
+			rows.Close()
+			return 0, "", err
+		}
+		batch = append(batch, rw)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, "", err
+	}
+	if len(batch) == 0 {
+		return 0, "", nil
+	}
+
+	var rewritten int
+	for _, rw := range batch {
+		sealed, index, changed, err := r.rewrapUID(rw.plainUID, rw.sealed, rw.index)
+		if err != nil {
+			return 0, "", fmt.Errorf("failed to re-encrypt Tuya account %s: %w", rw.id, err)
 		}
-		return Account{}, err
+		if !changed {
+			continue
+		}
+
+		update := `UPDATE tuya_app_accounts SET tuya_uid = NULL, tuya_uid_sealed = $2, tuya_uid_index = $3 WHERE id = $1`
+		if _, err := tx.Exec(ctx, update, rw.id, sealed, index); err != nil {
+			return 0, "", err
+		}
+		rewritten++
+	}
+
+	return rewritten, batch[len(batch)-1].id, tx.Commit(ctx)
+}
+
+// rewrapUID returns the sealed UID and index a row should hold under the
+// active key, and whether they differ from what it holds now.
+func (r *repository) rewrapUID(plainUID string, sealed []byte, index string) ([]byte, string, bool, error) {
+	if sealed == nil {
+		_, sealedUID, uidIndex, err := r.protectUID(plainUID)
+		return sealedUID, uidIndex, err == nil, err
 	}
 
+	uid, err := r.cipher.Open(sealed)
+	if err != nil {
+		return nil, "", false, err
+	}
+	rewrapped, changed, err := r.cipher.Rewrap(sealed)
+	if err != nil {
+		return nil, "", false, err
+	}
+	newIndex, err := r.cipher.Index(uid)
+	if err != nil {
+		return nil, "", false, err
+	}
+	return rewrapped, newIndex, changed || newIndex != index, nil
+}
+
+// protectUID returns the column values that store uid: the plaintext when no
+// key is configured, otherwise the sealed UID and its index.
+func (r *repository) protectUID(uid string) (string, []byte, string, error) {
+	if !r.cipher.Enabled() {
+		return uid, nil, "", nil
+	}
+
+	sealed, err := r.cipher.Seal([]byte(uid))
+	if err != nil {
+		return "", nil, "", fmt.Errorf("failed to encrypt Tuya UID: %w", err)
+	}
+	index, err := r.cipher.Index([]byte(uid))
+	if err != nil {
+		return "", nil, "", fmt.Errorf("failed to index Tuya UID: %w", err)
+	}
+	return "", sealed, index, nil
+}
+
+func (r *repository) scanAccount(row pgx.Row) (domain.TuyaAccount, error) {
+	var acc domain.TuyaAccount
+	var sealedUID []byte
+	err := row.Scan(&acc.ID, &acc.OwnerID, &acc.TenantID, &acc.TuyaUID, &sealedUID, &acc.Region, &acc.Label, &acc.IsDefault, &acc.CreatedAt, &acc.UpdatedAt)
+	if err != nil || sealedUID == nil {
+		return acc, err
+	}
+
+	uid, err := r.cipher.Open(sealedUID)
+	if err != nil {
+		return domain.TuyaAccount{}, fmt.Errorf("failed to decrypt Tuya UID of account %s: %w", acc.ID, err)
+	}
+	acc.TuyaUID = string(uid)
 	return acc, nil
 }
+
+func isNotFound(err error) bool {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == invalidTextRepresentation
+}
+
+func isConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation
+}
@@ -2,15 +2,23 @@ package account
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/pkg/api"
 )
 
 var ErrNotLinked = domain.ErrAccountNotLinked
 
+// Repository methods take the tenant explicitly; an empty tenant ID is the
+// deployment's default Tuya project.
 type Repository interface {
-	Get(ctx context.Context, ownerID string) (Account, error)
-	GetTuyaUID(ctx context.Context, ownerID string) (string, error)
+	GetDefault(ctx context.Context, tenantID, ownerID string) (domain.TuyaAccount, error)
+	List(ctx context.Context, tenantID, ownerID string) ([]domain.TuyaAccount, error)
+	ListAll(ctx context.Context) ([]domain.TuyaAccount, error)
+	Create(ctx context.Context, acc domain.TuyaAccount) (domain.TuyaAccount, error)
+	Update(ctx context.Context, tenantID, ownerID, accountID string, update domain.TuyaAccountUpdate) (domain.TuyaAccount, error)
+	Delete(ctx context.Context, tenantID, ownerID, accountID string) error
 }
 
 type service struct {
@@ -21,10 +29,58 @@ func NewService(repo Repository) *service {
 	return &service{repo: repo}
 }
 
-func (s *service) Get(ctx context.Context, ownerID string) (Account, error) {
-	return s.repo.Get(ctx, ownerID)
+func (s *service) Get(ctx context.Context, ownerID string) (domain.TuyaAccount, error) {
+	return s.repo.GetDefault(ctx, api.GetTenantIDFromContext(ctx), ownerID)
 }
 
-func (s *service) GetTuyaUID(ctx context.Context, ownerID string) (string, error) {
-	return s.repo.GetTuyaUID(ctx, ownerID)
+func (s *service) List(ctx context.Context, ownerID string) ([]domain.TuyaAccount, error) {
+	return s.repo.List(ctx, api.GetTenantIDFromContext(ctx), ownerID)
+}
+
+// ListLinked returns the owner's linked accounts, default first, or
+// ErrNotLinked when there are none.
+func (s *service) ListLinked(ctx context.Context, ownerID string) ([]domain.TuyaAccount, error) {
+	accounts, err := s.List(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if len(accounts) == 0 {
+		return nil, ErrNotLinked
+	}
+	return accounts, nil
+}
+
+// ListAll returns the linked accounts of every user in every tenant.
+func (s *service) ListAll(ctx context.Context) ([]domain.TuyaAccount, error) {
+	return s.repo.ListAll(ctx)
+}
+
+// Link attaches a Tuya account to a user. Platform callers may link into any
+// tenant; tenant callers only link into their own.
+func (s *service) Link(ctx context.Context, acc domain.TuyaAccount) (domain.TuyaAccount, error) {
+	if caller := api.GetTenantIDFromContext(ctx); caller != "" {
+		if acc.TenantID != "" && acc.TenantID != caller {
+			return domain.TuyaAccount{}, domain.ErrTenantNotFound
+		}
+		acc.TenantID = caller
+	}
+
+	if acc.Region != "" && !domain.ValidTuyaRegion(acc.Region) {
+		return domain.TuyaAccount{}, fmt.Errorf("%w: %q", domain.ErrInvalidRegion, acc.Region)
+	}
+	if acc.Label == "" {
+		acc.Label = domain.DefaultTuyaAccountLabel
+	}
+	return s.repo.Create(ctx, acc)
+}
+
+func (s *service) Update(ctx context.Context, ownerID, accountID string, update domain.TuyaAccountUpdate) (domain.TuyaAccount, error) {
+	if update.Region != "" && !domain.ValidTuyaRegion(update.Region) {
+		return domain.TuyaAccount{}, fmt.Errorf("%w: %q", domain.ErrInvalidRegion, update.Region)
+	}
+	return s.repo.Update(ctx, api.GetTenantIDFromContext(ctx), ownerID, accountID, update)
+}
+
+func (s *service) Unlink(ctx context.Context, ownerID, accountID string) error {
+	return s.repo.Delete(ctx, api.GetTenantIDFromContext(ctx), ownerID, accountID)
 }
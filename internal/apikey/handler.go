@@ -0,0 +1,138 @@
+package apikey
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/pkg/api"
+	"github.com/go-chi/chi/v5"
+)
+
+const defaultRotationOverlap = 24 * time.Hour
+
+type Service interface {
+	List(ctx context.Context) ([]domain.APIKey, error)
+	Mint(ctx context.Context, name string, scopes []string, tenantID string, expiresAt *time.Time) (domain.APIKey, string, error)
+	Rotate(ctx context.Context, id string, overlap time.Duration) (domain.APIKey, string, error)
+	Revoke(ctx context.Context, id string) error
+}
+
+type Handler struct {
+	svc Service
+}
+
+func NewHandler(svc Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+type mintedKey struct {
+	domain.APIKey
+	Key string `json:"key"`
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.svc.List(r.Context())
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("API keys retrieved successfully", keys, nil))
+}
+
+func (h *Handler) Mint(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		TenantID  string     `json:"tenant_id"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid request body", nil))
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Name cannot be empty", nil))
+		return
+	}
+
+	if len(req.Scopes) == 0 {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Scopes cannot be empty", nil))
+		return
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "expires_at must be in the future", nil))
+		return
+	}
+
+	key, raw, err := h.svc.Mint(r.Context(), req.Name, req.Scopes, strings.TrimSpace(req.TenantID), req.ExpiresAt)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusCreated, api.NewSuccessResponse("API key created; store the key now, it cannot be retrieved again", mintedKey{APIKey: key, Key: raw}, nil))
+}
+
+func (h *Handler) Rotate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		OverlapSeconds *int `json:"overlap_seconds"`
+	}
+
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid request body", nil))
+			return
+		}
+	}
+
+	overlap := defaultRotationOverlap
+	if req.OverlapSeconds != nil {
+		if *req.OverlapSeconds < 0 {
+			api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "overlap_seconds cannot be negative", nil))
+			return
+		}
+		overlap = time.Duration(*req.OverlapSeconds) * time.Second
+	}
+
+	key, raw, err := h.svc.Rotate(r.Context(), chi.URLParam(r, "keyId"), overlap)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusCreated, api.NewSuccessResponse("API key rotated; store the key now, it cannot be retrieved again", mintedKey{APIKey: key, Key: raw}, map[string]any{
+		"previous_key_expires_at": time.Now().Add(overlap),
+	}))
+}
+
+func (h *Handler) Revoke(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.Revoke(r.Context(), chi.URLParam(r, "keyId")); err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("API key revoked", nil, nil))
+}
+
+func respondError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrAPIKeyNotFound):
+		api.Respond(w, http.StatusNotFound, api.NewErrorResponse("NOT_FOUND", "API key not found", nil))
+	case errors.Is(err, domain.ErrTenantNotFound):
+		api.Respond(w, http.StatusNotFound, api.NewErrorResponse("NOT_FOUND", "Tenant not found", nil))
+	case errors.Is(err, domain.ErrInvalidScope):
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", err.Error(), nil))
+	default:
+		api.Respond(w, http.StatusInternalServerError, api.NewErrorResponse("INTERNAL_ERROR", err.Error(), nil))
+	}
+}
@@ -0,0 +1,140 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	invalidTextRepresentation = "22P02"
+	foreignKeyViolation       = "23503"
+)
+
+const keyColumns = `id, name, prefix, key_hash, scopes, COALESCE(tenant_id::text, ''), created_at, expires_at, last_used_at, revoked_at`
+
+type repository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRepository(pool *pgxpool.Pool) *repository {
+	return &repository{pool: pool}
+}
+
+func (r *repository) Create(ctx context.Context, key domain.APIKey) (domain.APIKey, error) {
+	query := `INSERT INTO api_keys (name, prefix, key_hash, scopes, tenant_id, expires_at) VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, $6) RETURNING ` + keyColumns
+
+	key, err := scanKey(r.pool.QueryRow(ctx, query, key.Name, key.Prefix, key.Hash, key.Scopes, key.TenantID, key.ExpiresAt))
+	if err != nil {
+		if isNotFound(err) || isForeignKeyViolation(err) {
+			return domain.APIKey{}, domain.ErrTenantNotFound
+		}
+		return domain.APIKey{}, err
+	}
+	return key, nil
+}
+
+// GetByPrefix only finds keys whose tenant, if any, is still active.
+func (r *repository) GetByPrefix(ctx context.Context, prefix string) (domain.APIKey, error) {
+	query := `
+		SELECT ` + keyColumns + ` FROM api_keys
+		WHERE prefix = $1 AND (tenant_id IS NULL OR tenant_id IN (SELECT id FROM tenants WHERE disabled_at IS NULL))`
+
+	key, err := scanKey(r.pool.QueryRow(ctx, query, prefix))
+	if err != nil {
+		if isNotFound(err) {
+			return domain.APIKey{}, domain.ErrAPIKeyNotFound
+		}
+		return domain.APIKey{}, err
+	}
+	return key, nil
+}
+
+func (r *repository) Get(ctx context.Context, id string) (domain.APIKey, error) {
+	query := `SELECT ` + keyColumns + ` FROM api_keys WHERE id = $1`
+
+	key, err := scanKey(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if isNotFound(err) {
+			return domain.APIKey{}, domain.ErrAPIKeyNotFound
+		}
+		return domain.APIKey{}, err
+	}
+	return key, nil
+}
+
+// List returns the keys of a tenant, or every key when tenantID is empty.
+func (r *repository) List(ctx context.Context, tenantID string) ([]domain.APIKey, error) {
+	query := `SELECT ` + keyColumns + ` FROM api_keys WHERE $1 = '' OR tenant_id::text = $1 ORDER BY created_at DESC`
+
+	rows, err := r.pool.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []domain.APIKey{}
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *repository) Revoke(ctx context.Context, id string) error {
+	query := `UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+
+	tag, err := r.pool.Exec(ctx, query, id)
+	if err != nil {
+		if isNotFound(err) {
+			return domain.ErrAPIKeyNotFound
+		}
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// ExpireAt shortens the lifetime of a key, never extending an earlier expiry.
+func (r *repository) ExpireAt(ctx context.Context, id string, expiresAt time.Time) error {
+	query := `UPDATE api_keys SET expires_at = LEAST(COALESCE(expires_at, $2), $2) WHERE id = $1`
+	_, err := r.pool.Exec(ctx, query, id, expiresAt)
+	return err
+}
+
+// TouchLastUsed records usage at most once per interval to avoid a write on
+// every request.
+func (r *repository) TouchLastUsed(ctx context.Context, id string, interval time.Duration) error {
+	query := `UPDATE api_keys SET last_used_at = NOW() WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - $2 * INTERVAL '1 millisecond')`
+	_, err := r.pool.Exec(ctx, query, id, interval.Milliseconds())
+	return err
+}
+
+func scanKey(row pgx.Row) (domain.APIKey, error) {
+	var k domain.APIKey
+	err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.Hash, &k.Scopes, &k.TenantID, &k.CreatedAt, &k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt)
+	return k, err
+}
+
+func isNotFound(err error) bool {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == invalidTextRepresentation
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation
+}
@@ -0,0 +1,216 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/pkg/api"
+)
+
+const (
+	keyPrefix        = "zee"
+	lastUsedInterval = time.Minute
+)
+
+type Repository interface {
+	Create(ctx context.Context, key domain.APIKey) (domain.APIKey, error)
+	Get(ctx context.Context, id string) (domain.APIKey, error)
+	GetByPrefix(ctx context.Context, prefix string) (domain.APIKey, error)
+	List(ctx context.Context, tenantID string) ([]domain.APIKey, error)
+	Revoke(ctx context.Context, id string) error
+	ExpireAt(ctx context.Context, id string, expiresAt time.Time) error
+	TouchLastUsed(ctx context.Context, id string, interval time.Duration) error
+}
+
+type service struct {
+	repo      Repository
+	legacyKey string
+
+	// touched holds when each key's usage was last recorded, so the database
+	// is written at most once per lastUsedInterval and key.
+	touchedMu sync.Mutex
+	touched   map[string]time.Time
+}
+
+// legacyScopes are granted to the deprecated shared API_KEY. They cover what
+// existing clients used it for but not administration, which needs a minted
+// key.
+var legacyScopes = []string{domain.ScopeDevicesRead, domain.ScopeCommandsSend}
+
+// NewService creates the API key service. legacyKey is the optional, deprecated
+// shared API_KEY secret; it keeps existing clients working until they are
+// migrated to minted keys.
+func NewService(repo Repository, legacyKey string) *service {
+	return &service{repo: repo, legacyKey: legacyKey, touched: make(map[string]time.Time)}
+}
+
+func (s *service) Authenticate(ctx context.Context, raw string) (domain.APIKey, error) {
+	if s.legacyKey != "" && subtle.ConstantTimeCompare([]byte(raw), []byte(s.legacyKey)) == 1 {
+		log.Printf("Warning: request authenticated with the deprecated API_KEY; migrate the client to a minted API key")
+		return domain.APIKey{ID: "legacy", Name: "legacy", Scopes: legacyScopes}, nil
+	}
+
+	prefix, ok := parsePrefix(raw)
+	if !ok {
+		return domain.APIKey{}, domain.ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.GetByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, domain.ErrAPIKeyNotFound) {
+			return domain.APIKey{}, domain.ErrInvalidAPIKey
+		}
+		return domain.APIKey{}, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashKey(raw)), []byte(key.Hash)) != 1 || !key.Active(time.Now()) {
+		return domain.APIKey{}, domain.ErrInvalidAPIKey
+	}
+
+	s.touchLastUsed(key.ID)
+	return key, nil
+}
+
+// touchLastUsed records usage in the background unless this instance already
+// did so within lastUsedInterval.
+func (s *service) touchLastUsed(id string) {
+	now := time.Now()
+
+	s.touchedMu.Lock()
+	if last, ok := s.touched[id]; ok && now.Sub(last) < lastUsedInterval {
+		s.touchedMu.Unlock()
+		return
+	}
+	s.touched[id] = now
+	s.touchedMu.Unlock()
+
+	go func() {
+		if err := s.repo.TouchLastUsed(context.Background(), id, lastUsedInterval); err != nil {
+			log.Printf("Warning: failed to record api key usage for %s: %v", id, err)
+		}
+	}()
+}
+
+// Mint creates a key and returns it together with the plaintext secret,
+// which is never stored and cannot be retrieved again. Platform callers may
+// bind the key to any tenant; tenant callers only mint keys for their own.
+func (s *service) Mint(ctx context.Context, name string, scopes []string, tenantID string, expiresAt *time.Time) (domain.APIKey, string, error) {
+	if caller := api.GetTenantIDFromContext(ctx); caller != "" {
+		if tenantID != "" && tenantID != caller {
+			return domain.APIKey{}, "", domain.ErrTenantNotFound
+		}
+		tenantID = caller
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(domain.Scopes, scope) {
+			return domain.APIKey{}, "", fmt.Errorf("%w: %q", domain.ErrInvalidScope, scope)
+		}
+	}
+
+	prefix, raw, err := generateKey()
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+
+	key, err := s.repo.Create(ctx, domain.APIKey{
+		Name:      name,
+		Prefix:    prefix,
+		Hash:      hashKey(raw),
+		Scopes:    scopes,
+		TenantID:  tenantID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+
+	return key, raw, nil
+}
+
+// Rotate mints a replacement with the same name and scopes and lets the old
+// key keep working for overlap so clients can roll over without downtime.
+func (s *service) Rotate(ctx context.Context, id string, overlap time.Duration) (domain.APIKey, string, error) {
+	old, err := s.get(ctx, id)
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+	if !old.Active(time.Now()) {
+		return domain.APIKey{}, "", domain.ErrAPIKeyNotFound
+	}
+
+	key, raw, err := s.Mint(ctx, old.Name, old.Scopes, old.TenantID, old.ExpiresAt)
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+
+	if err := s.repo.ExpireAt(ctx, old.ID, time.Now().Add(overlap)); err != nil {
+		return domain.APIKey{}, "", fmt.Errorf("failed to schedule expiry of rotated key: %w", err)
+	}
+
+	return key, raw, nil
+}
+
+func (s *service) List(ctx context.Context) ([]domain.APIKey, error) {
+	return s.repo.List(ctx, api.GetTenantIDFromContext(ctx))
+}
+
+func (s *service) Revoke(ctx context.Context, id string) error {
+	if _, err := s.get(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.Revoke(ctx, id)
+}
+
+// get loads a key, hiding keys of other tenants from tenant callers.
+func (s *service) get(ctx context.Context, id string) (domain.APIKey, error) {
+	key, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return domain.APIKey{}, err
+	}
+
+	if caller := api.GetTenantIDFromContext(ctx); caller != "" && key.TenantID != caller {
+		return domain.APIKey{}, domain.ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+func generateKey() (string, string, error) {
+	prefixBytes := make([]byte, 6)
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", fmt.Errorf("failed to generate key prefix: %w", err)
+	}
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", fmt.Errorf("failed to generate key secret: %w", err)
+	}
+
+	prefix := hex.EncodeToString(prefixBytes)
+	raw := keyPrefix + "_" + prefix + "_" + base64.RawURLEncoding.EncodeToString(secretBytes)
+	return prefix, raw, nil
+}
+
+func parsePrefix(raw string) (string, bool) {
+	parts := strings.SplitN(raw, "_", 3)
+	if len(parts) != 3 || parts[0] != keyPrefix || parts[1] == "" || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+func hashKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
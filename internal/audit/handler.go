@@ -0,0 +1,92 @@
+package audit
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/pkg/api"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+type Service interface {
+	ListCommands(ctx context.Context, filter domain.CommandAuditFilter) (domain.CommandAuditPage, error)
+}
+
+type Handler struct {
+	svc Service
+}
+
+func NewHandler(svc Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+func (h *Handler) ListCommands(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := domain.CommandAuditFilter{
+		OwnerID:   q.Get("owner_id"),
+		TuyaUID:   q.Get("tuya_uid"),
+		DeviceID:  q.Get("device_id"),
+		RequestID: q.Get("request_id"),
+		Tid:       q.Get("tid"),
+	}
+
+	if filter.OwnerID != "" && !uuidPattern.MatchString(filter.OwnerID) {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid owner_id, expected a UUID", nil))
+		return
+	}
+
+	var err error
+	if raw := q.Get("success"); raw != "" {
+		success, err := strconv.ParseBool(raw)
+		if err != nil {
+			api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid success, expected true or false", nil))
+			return
+		}
+		filter.Success = &success
+	}
+
+	if raw := q.Get("from"); raw != "" {
+		if filter.From, err = time.Parse(time.RFC3339, raw); err != nil {
+			api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid from, expected RFC3339", nil))
+			return
+		}
+	}
+
+	if raw := q.Get("to"); raw != "" {
+		if filter.To, err = time.Parse(time.RFC3339, raw); err != nil {
+			api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid to, expected RFC3339", nil))
+			return
+		}
+	}
+
+	if raw := q.Get("cursor"); raw != "" {
+		if filter.BeforeID, err = strconv.ParseInt(raw, 10, 64); err != nil || filter.BeforeID <= 0 {
+			api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid cursor", nil))
+			return
+		}
+	}
+
+	if raw := q.Get("limit"); raw != "" {
+		if filter.Limit, err = strconv.Atoi(raw); err != nil || filter.Limit <= 0 {
+			api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid limit", nil))
+			return
+		}
+	}
+
+	page, err := h.svc.ListCommands(r.Context(), filter)
+	if err != nil {
+		log.Printf("failed to list command audit log: %v", err)
+		api.Respond(w, http.StatusInternalServerError, api.NewErrorResponse("INTERNAL_ERROR", "Failed to list command audit log", nil))
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Command audit log retrieved successfully", page.Entries, map[string]any{
+		"next_cursor": page.NextCursor,
+	}))
+}
@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type repository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRepository(pool *pgxpool.Pool) *repository {
+	return &repository{pool: pool}
+}
+
+func (r *repository) Insert(ctx context.Context, entry domain.CommandAudit) error {
+	commands, err := json.Marshal(entry.Commands)
+	if err != nil {
+		return fmt.Errorf("failed to marshal commands: %w", err)
+	}
+
+	var result []byte
+	if len(entry.Result) > 0 {
+		result = entry.Result
+	}
+
+	query := `
+		INSERT INTO command_audit_log (tenant_id, owner_id, tuya_uid, device_id, remote_id, commands, source, request_id, success, result, error, tid, latency_ms)
+		VALUES (NULLIF($1, '')::uuid, $2, $3, $4, NULLIF($5, ''), $6, $7, NULLIF($8, ''), $9, $10, NULLIF($11, ''), NULLIF($12, ''), $13)`
+
+	_, err = r.pool.Exec(ctx, query, entry.TenantID, entry.OwnerID, entry.TuyaUID, entry.DeviceID, entry.RemoteID, commands, entry.Source,
+		entry.RequestID, entry.Success, result, entry.Error, entry.Tid, entry.LatencyMs)
+	return err
+}
+
+func (r *repository) List(ctx context.Context, filter domain.CommandAuditFilter) ([]domain.CommandAudit, error) {
+	var conditions []string
+	var args []any
+	where := func(column string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf("%s $%d", column, len(args)))
+	}
+
+	if filter.TenantID != "" {
+		where("tenant_id =", filter.TenantID)
+	}
+	if filter.OwnerID != "" {
+		where("owner_id =", filter.OwnerID)
+	}
+	if filter.TuyaUID != "" {
+		where("tuya_uid =", filter.TuyaUID)
+	}
+	if filter.DeviceID != "" {
+		where("device_id =", filter.DeviceID)
+	}
+	if filter.RequestID != "" {
+		where("request_id =", filter.RequestID)
+	}
+	if filter.Tid != "" {
+		where("tid =", filter.Tid)
+	}
+	if filter.Success != nil {
+		where("success =", *filter.Success)
+	}
+	if !filter.From.IsZero() {
+		where("created_at >=", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("created_at <", filter.To)
+	}
+	if filter.BeforeID > 0 {
+		where("id <", filter.BeforeID)
+	}
+
+	query := `
+		SELECT id, COALESCE(tenant_id::text, ''), owner_id, tuya_uid, device_id, COALESCE(remote_id, ''), commands, source, COALESCE(request_id, ''), success, result,
+		       COALESCE(error, ''), COALESCE(tid, ''), latency_ms, created_at
+		FROM command_audit_log`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args))
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []domain.CommandAudit{}
+	for rows.Next() {
+		var e domain.CommandAudit
+		var commands []byte
+		if err := rows.Scan(&e.ID, &e.TenantID, &e.OwnerID, &e.TuyaUID, &e.DeviceID, &e.RemoteID, &commands, &e.Source, &e.RequestID, &e.Success,
+			&e.Result, &e.Error, &e.Tid, &e.LatencyMs, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(commands, &e.Commands); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal commands: %w", err)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
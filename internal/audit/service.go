@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"context"
+	"log"
+	"strconv"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/pkg/api"
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+type Repository interface {
+	Insert(ctx context.Context, entry domain.CommandAudit) error
+	List(ctx context.Context, filter domain.CommandAuditFilter) ([]domain.CommandAudit, error)
+}
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) *service {
+	return &service{repo: repo}
+}
+
+// RecordCommand persists a command attempt. Failures are logged rather than
+// returned so that auditing never blocks a command that already reached Tuya.
+func (s *service) RecordCommand(ctx context.Context, entry domain.CommandAudit) {
+	if entry.RequestID == "" {
+		entry.RequestID = chiMiddleware.GetReqID(ctx)
+	}
+	if entry.TenantID == "" {
+		entry.TenantID = api.GetTenantIDFromContext(ctx)
+	}
+
+	if err := s.repo.Insert(context.WithoutCancel(ctx), entry); err != nil {
+		log.Printf("Warning: failed to record command audit for device %s: %v", entry.DeviceID, err)
+	}
+}
+
+// ListCommands lists audit entries. Tenant callers only see their tenant's
+// entries; platform callers see every tenant.
+func (s *service) ListCommands(ctx context.Context, filter domain.CommandAuditFilter) (domain.CommandAuditPage, error) {
+	filter.TenantID = api.GetTenantIDFromContext(ctx)
+	if filter.Limit <= 0 {
+		filter.Limit = defaultListLimit
+	}
+	if filter.Limit > maxListLimit {
+		filter.Limit = maxListLimit
+	}
+
+	entries, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return domain.CommandAuditPage{}, err
+	}
+
+	page := domain.CommandAuditPage{Entries: entries}
+	if len(entries) == filter.Limit {
+		page.NextCursor = strconv.FormatInt(entries[len(entries)-1].ID, 10)
+	}
+	return page, nil
+}
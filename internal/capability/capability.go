@@ -0,0 +1,278 @@
+// Package capability maps Tuya data points to normalized device capabilities
+// and translates capability commands back into data points.
+package capability
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/avagenc/zee-api/internal/domain"
+)
+
+var gangSwitch = regexp.MustCompile(`^switch_\d+$`)
+
+// Codes in order of preference: newer instruction sets first.
+var (
+	onOffCodes      = []string{"switch_led", "switch"}
+	brightnessCodes = []string{"bright_value_v2", "bright_value"}
+	colorTempCodes  = []string{"temp_value_v2", "temp_value"}
+	colorCodes      = []string{"colour_data_v2", "colour_data"}
+)
+
+// Derive returns the capabilities a device's reported status exposes.
+func Derive(status []domain.DataPoint, spec Spec) []domain.Capability {
+	values := statusValues(status)
+
+	var caps []domain.Capability
+
+	for _, dp := range status {
+		on, ok := dp.Value.(bool)
+		if !ok {
+			continue
+		}
+		switch {
+		case slices.Contains(onOffCodes, dp.Code):
+			caps = append(caps, domain.Capability{Type: domain.CapabilityOnOff, State: domain.OnOffState{On: on}})
+		case gangSwitch.MatchString(dp.Code):
+			caps = append(caps, domain.Capability{Type: domain.CapabilityOnOff, Instance: dp.Code, State: domain.OnOffState{On: on}})
+		}
+	}
+
+	if code, raw, ok := firstNumber(values, brightnessCodes); ok {
+		caps = append(caps, domain.Capability{Type: domain.CapabilityBrightness, State: domain.LevelState{Percent: spec.integer(code).toPercent(raw)}})
+	}
+
+	if code, raw, ok := firstNumber(values, colorTempCodes); ok {
+		percent := spec.integer(code).toPercent(raw)
+		caps = append(caps, domain.Capability{Type: domain.CapabilityColorTemperature, State: domain.ColorTemperatureState{Percent: percent, Kelvin: percentToKelvin(percent)}})
+	}
+
+	if code, ok := firstPresent(values, colorCodes); ok {
+		if color, err := decodeColor(values[code], spec.color(code)); err == nil {
+			caps = append(caps, domain.Capability{Type: domain.CapabilityColor, State: color})
+		}
+	}
+
+	if _, ok := values["temp_set"]; ok {
+		var state domain.ThermostatState
+		if raw, ok := number(values["temp_set"]); ok {
+			target := spec.integer("temp_set").toValue(raw)
+			state.Target = &target
+		}
+		if raw, ok := number(values["temp_current"]); ok {
+			current := spec.integer("temp_current").toValue(raw)
+			state.Current = &current
+		}
+		state.Mode, _ = values["mode"].(string)
+		caps = append(caps, domain.Capability{Type: domain.CapabilityThermostat, State: state})
+	}
+
+	if code, raw, ok := firstNumber(values, []string{"percent_state", "percent_control"}); ok {
+		position := spec.integer(code).toPercent(raw)
+		caps = append(caps, domain.Capability{Type: domain.CapabilityCover, State: domain.CoverState{Position: &position}})
+	} else if _, ok := values["control"]; ok {
+		caps = append(caps, domain.Capability{Type: domain.CapabilityCover, State: domain.CoverState{}})
+	}
+
+	var power domain.PowerMeteringState
+	if raw, ok := number(values["cur_power"]); ok {
+		watts := spec.integer("cur_power").toValue(raw)
+		power.Watts = &watts
+	}
+	if raw, ok := number(values["cur_voltage"]); ok {
+		volts := spec.integer("cur_voltage").toValue(raw)
+		power.Volts = &volts
+	}
+	if raw, ok := number(values["cur_current"]); ok {
+		amps := spec.integer("cur_current").toValue(raw) / 1000
+		power.Amps = &amps
+	}
+	if power != (domain.PowerMeteringState{}) {
+		caps = append(caps, domain.Capability{Type: domain.CapabilityPowerMetering, State: power})
+	}
+
+	return caps
+}
+
+// Translate turns capability commands into the data points the device
+// understands, choosing codes from its reported status.
+func Translate(status []domain.DataPoint, spec Spec, commands []domain.CapabilityCommand) ([]domain.DataPoint, error) {
+	values := statusValues(status)
+
+	var points []domain.DataPoint
+	for _, cmd := range commands {
+		translated, err := translate(values, spec, cmd)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", cmd.Type, err)
+		}
+		points = append(points, translated...)
+	}
+	return points, nil
+}
+
+func translate(values map[string]any, spec Spec, cmd domain.CapabilityCommand) ([]domain.DataPoint, error) {
+	switch cmd.Type {
+	case domain.CapabilityOnOff:
+		var state struct {
+			On *bool `json:"on"`
+		}
+		if err := decodeState(cmd.State, &state); err != nil || state.On == nil {
+			return nil, fmt.Errorf("%w: state.on is required", domain.ErrInvalidCapability)
+		}
+
+		code := cmd.Instance
+		if code == "" {
+			var ok bool
+			if code, ok = firstPresent(values, append(slices.Clone(onOffCodes), "switch_1")); !ok {
+				return nil, domain.ErrUnsupportedCapability
+			}
+		} else if _, ok := values[code]; !ok || !(slices.Contains(onOffCodes, code) || gangSwitch.MatchString(code)) {
+			return nil, fmt.Errorf("%w: unknown instance %q", domain.ErrUnsupportedCapability, code)
+		}
+		return []domain.DataPoint{{Code: code, Value: *state.On}}, nil
+
+	case domain.CapabilityBrightness:
+		var state struct {
+			Percent *float64 `json:"percent"`
+		}
+		if err := decodeState(cmd.State, &state); err != nil || state.Percent == nil || *state.Percent < 0 || *state.Percent > 100 {
+			return nil, fmt.Errorf("%w: state.percent must be between 0 and 100", domain.ErrInvalidCapability)
+		}
+		return brightnessPoints(values, spec, *state.Percent)
+
+	case domain.CapabilityColorTemperature:
+		var state struct {
+			Percent *float64 `json:"percent"`
+			Kelvin  *float64 `json:"kelvin"`
+		}
+		if err := decodeState(cmd.State, &state); err != nil {
+			return nil, fmt.Errorf("%w: %v", domain.ErrInvalidCapability, err)
+		}
+		switch {
+		case state.Kelvin != nil && *state.Kelvin > 0:
+			return whitePoints(values, spec, kelvinToPercent(*state.Kelvin))
+		case state.Percent != nil && *state.Percent >= 0 && *state.Percent <= 100:
+			return whitePoints(values, spec, *state.Percent)
+		}
+		return nil, fmt.Errorf("%w: state.percent between 0 and 100 or a positive state.kelvin is required", domain.ErrInvalidCapability)
+
+	case domain.CapabilityColor:
+		var state domain.ColorState
+		if err := decodeState(cmd.State, &state); err != nil {
+			return nil, fmt.Errorf("%w: %v", domain.ErrInvalidCapability, err)
+		}
+		return colorPoints(values, spec, state)
+
+	case domain.CapabilityThermostat:
+		if _, ok := values["temp_set"]; !ok {
+			return nil, domain.ErrUnsupportedCapability
+		}
+
+		var state domain.ThermostatState
+		if err := decodeState(cmd.State, &state); err != nil {
+			return nil, fmt.Errorf("%w: %v", domain.ErrInvalidCapability, err)
+		}
+
+		var points []domain.DataPoint
+		if state.Target != nil {
+			r := spec.integer("temp_set")
+			raw := r.fromValue(*state.Target)
+			if !r.contains(raw) {
+				return nil, fmt.Errorf("%w: target must be between %g and %g", domain.ErrInvalidCapability, r.toValue(r.Min), r.toValue(r.Max))
+			}
+			points = append(points, domain.DataPoint{Code: "temp_set", Value: raw})
+		}
+		if state.Mode != "" {
+			if _, ok := values["mode"]; !ok {
+				return nil, fmt.Errorf("%w: device has no modes", domain.ErrUnsupportedCapability)
+			}
+			if modes := spec.enum("mode"); modes != nil && !slices.Contains(modes, state.Mode) {
+				return nil, fmt.Errorf("%w: mode must be one of %v", domain.ErrInvalidCapability, modes)
+			}
+			points = append(points, domain.DataPoint{Code: "mode", Value: state.Mode})
+		}
+		if len(points) == 0 {
+			return nil, fmt.Errorf("%w: state.target or state.mode is required", domain.ErrInvalidCapability)
+		}
+		return points, nil
+
+	case domain.CapabilityCover:
+		var state domain.CoverState
+		if err := decodeState(cmd.State, &state); err != nil {
+			return nil, fmt.Errorf("%w: %v", domain.ErrInvalidCapability, err)
+		}
+
+		switch {
+		case state.Position != nil:
+			if _, ok := values["percent_control"]; !ok {
+				return nil, fmt.Errorf("%w: device has no position control", domain.ErrUnsupportedCapability)
+			}
+			if *state.Position < 0 || *state.Position > 100 {
+				return nil, fmt.Errorf("%w: state.position must be between 0 and 100", domain.ErrInvalidCapability)
+			}
+			return []domain.DataPoint{{Code: "percent_control", Value: spec.integer("percent_control").fromPercent(*state.Position)}}, nil
+		case state.Action != "":
+			if _, ok := values["control"]; !ok {
+				return nil, domain.ErrUnsupportedCapability
+			}
+			if !slices.Contains([]string{domain.CoverActionOpen, domain.CoverActionClose, domain.CoverActionStop}, state.Action) {
+				return nil, fmt.Errorf("%w: state.action must be open, close or stop", domain.ErrInvalidCapability)
+			}
+			return []domain.DataPoint{{Code: "control", Value: state.Action}}, nil
+		}
+		return nil, fmt.Errorf("%w: state.position or state.action is required", domain.ErrInvalidCapability)
+
+	case domain.CapabilityPowerMetering:
+		return nil, fmt.Errorf("%w: power metering is read-only", domain.ErrInvalidCapability)
+	}
+
+	return nil, fmt.Errorf("%w: unknown capability type %q", domain.ErrInvalidCapability, cmd.Type)
+}
+
+func decodeState(raw json.RawMessage, v any) error {
+	if len(raw) == 0 {
+		return fmt.Errorf("state is required")
+	}
+	return json.Unmarshal(raw, v)
+}
+
+func statusValues(status []domain.DataPoint) map[string]any {
+	values := make(map[string]any, len(status))
+	for _, dp := range status {
+		values[dp.Code] = dp.Value
+	}
+	return values
+}
+
+func firstPresent(values map[string]any, codes []string) (string, bool) {
+	for _, code := range codes {
+		if _, ok := values[code]; ok {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+func firstNumber(values map[string]any, codes []string) (string, float64, bool) {
+	for _, code := range codes {
+		if raw, ok := number(values[code]); ok {
+			return code, raw, true
+		}
+	}
+	return "", 0, false
+}
+
+func number(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
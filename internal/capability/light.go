@@ -0,0 +1,325 @@
+package capability
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/avagenc/zee-api/internal/domain"
+)
+
+// Tuya maps a light's color temperature range onto temp_value without saying
+// what that range is. Most white-spectrum lights span roughly these values.
+const (
+	warmestKelvin = 2700
+	coolestKelvin = 6500
+)
+
+const (
+	workModeWhite  = "white"
+	workModeColour = "colour"
+)
+
+// TranslateLight turns a light command into data points, choosing between the
+// v2 and v1 instruction sets from the light's reported status.
+func TranslateLight(status []domain.DataPoint, spec Spec, cmd domain.LightCommand) ([]domain.DataPoint, error) {
+	values := statusValues(status)
+
+	if cmd.Color != nil && cmd.Kelvin != nil {
+		return nil, fmt.Errorf("%w: color and kelvin cannot be combined", domain.ErrInvalidCapability)
+	}
+	if cmd.Brightness != nil && (*cmd.Brightness < 0 || *cmd.Brightness > 100) {
+		return nil, fmt.Errorf("%w: brightness must be between 0 and 100", domain.ErrInvalidCapability)
+	}
+
+	var points []domain.DataPoint
+	if cmd.On != nil {
+		code, ok := firstPresent(values, onOffCodes)
+		if !ok {
+			return nil, fmt.Errorf("%w: light has no switch", domain.ErrUnsupportedCapability)
+		}
+		points = append(points, domain.DataPoint{Code: code, Value: *cmd.On})
+	}
+
+	switch {
+	case cmd.Color != nil:
+		color, err := parseColor(*cmd.Color)
+		if err != nil {
+			return nil, err
+		}
+		if cmd.Brightness != nil {
+			color.Value = *cmd.Brightness
+		}
+		set, err := colorPoints(values, spec, color)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, set...)
+
+	case cmd.Kelvin != nil:
+		if *cmd.Kelvin <= 0 {
+			return nil, fmt.Errorf("%w: kelvin must be positive", domain.ErrInvalidCapability)
+		}
+		set, err := whitePoints(values, spec, kelvinToPercent(*cmd.Kelvin))
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, set...)
+		if cmd.Brightness != nil {
+			code, ok := firstPresent(values, brightnessCodes)
+			if !ok {
+				return nil, fmt.Errorf("%w: light has no white brightness", domain.ErrUnsupportedCapability)
+			}
+			points = append(points, domain.DataPoint{Code: code, Value: spec.integer(code).fromPercent(*cmd.Brightness)})
+		}
+
+	case cmd.Brightness != nil:
+		set, err := brightnessPoints(values, spec, *cmd.Brightness)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, set...)
+	}
+
+	if len(points) == 0 {
+		return nil, fmt.Errorf("%w: on, brightness, color or kelvin is required", domain.ErrInvalidCapability)
+	}
+	return points, nil
+}
+
+// colorPoints switches the light to color mode and sets its color.
+func colorPoints(values map[string]any, spec Spec, color domain.ColorState) ([]domain.DataPoint, error) {
+	code, ok := firstPresent(values, colorCodes)
+	if !ok {
+		return nil, domain.ErrUnsupportedCapability
+	}
+
+	legacy := false
+	if s, ok := values[code].(string); ok {
+		legacy = isLegacyColor(s)
+	}
+
+	value, err := encodeColor(color, spec.color(code), legacy)
+	if err != nil {
+		return nil, err
+	}
+	return append(workMode(values, spec, workModeColour), domain.DataPoint{Code: code, Value: value}), nil
+}
+
+// whitePoints switches the light to white mode at a color temperature
+// percentage.
+func whitePoints(values map[string]any, spec Spec, percent float64) ([]domain.DataPoint, error) {
+	code, ok := firstPresent(values, colorTempCodes)
+	if !ok {
+		return nil, domain.ErrUnsupportedCapability
+	}
+	return append(workMode(values, spec, workModeWhite), domain.DataPoint{Code: code, Value: spec.integer(code).fromPercent(percent)}), nil
+}
+
+// brightnessPoints sets brightness in the light's current mode. In color mode
+// Tuya ignores bright_value and takes brightness from the color's value.
+func brightnessPoints(values map[string]any, spec Spec, percent float64) ([]domain.DataPoint, error) {
+	if values["work_mode"] == workModeColour {
+		if code, ok := firstPresent(values, colorCodes); ok {
+			if color, err := decodeColor(values[code], spec.color(code)); err == nil {
+				color.Value = percent
+				return colorPoints(values, spec, color)
+			}
+		}
+	}
+
+	code, ok := firstPresent(values, brightnessCodes)
+	if !ok {
+		return nil, domain.ErrUnsupportedCapability
+	}
+	return []domain.DataPoint{{Code: code, Value: spec.integer(code).fromPercent(percent)}}, nil
+}
+
+// workMode returns the work_mode data point selecting mode, or nothing when
+// the light has no work_mode or does not list mode among its members.
+func workMode(values map[string]any, spec Spec, mode string) []domain.DataPoint {
+	if _, ok := values["work_mode"]; !ok {
+		return nil
+	}
+	if modes := spec.enum("work_mode"); modes != nil && !slices.Contains(modes, mode) {
+		return nil
+	}
+	return []domain.DataPoint{{Code: "work_mode", Value: mode}}
+}
+
+func kelvinToPercent(kelvin float64) float64 {
+	percent := (kelvin - warmestKelvin) / (coolestKelvin - warmestKelvin) * 100
+	return math.Max(0, math.Min(100, percent))
+}
+
+func percentToKelvin(percent float64) int {
+	return int(math.Round(warmestKelvin + percent/100*(coolestKelvin-warmestKelvin)))
+}
+
+func parseColor(c domain.LightColor) (domain.ColorState, error) {
+	given := 0
+	for _, set := range []bool{c.Hex != "", c.RGB != nil, c.HSV != nil} {
+		if set {
+			given++
+		}
+	}
+	if given != 1 {
+		return domain.ColorState{}, fmt.Errorf("%w: color needs exactly one of hex, rgb or hsv", domain.ErrInvalidCapability)
+	}
+
+	switch {
+	case c.HSV != nil:
+		return *c.HSV, nil
+	case c.RGB != nil:
+		if !inByte(c.RGB.R) || !inByte(c.RGB.G) || !inByte(c.RGB.B) {
+			return domain.ColorState{}, fmt.Errorf("%w: rgb components must be between 0 and 255", domain.ErrInvalidCapability)
+		}
+		return rgbToHSV(*c.RGB), nil
+	}
+
+	rgb, err := parseHex(c.Hex)
+	if err != nil {
+		return domain.ColorState{}, err
+	}
+	return rgbToHSV(rgb), nil
+}
+
+func parseHex(s string) (domain.RGB, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 3 {
+		return domain.RGB{}, fmt.Errorf("%w: hex must be #rrggbb or #rgb", domain.ErrInvalidCapability)
+	}
+	return domain.RGB{R: int(b[0]), G: int(b[1]), B: int(b[2])}, nil
+}
+
+func inByte(v int) bool {
+	return v >= 0 && v <= 255
+}
+
+func rgbToHSV(c domain.RGB) domain.ColorState {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	maxC := math.Max(r, math.Max(g, b))
+	delta := maxC - math.Min(r, math.Min(g, b))
+
+	var hue float64
+	switch {
+	case delta == 0:
+	case maxC == r:
+		hue = 60 * math.Mod((g-b)/delta, 6)
+	case maxC == g:
+		hue = 60 * ((b-r)/delta + 2)
+	default:
+		hue = 60 * ((r-g)/delta + 4)
+	}
+	if hue < 0 {
+		hue += 360
+	}
+
+	var saturation float64
+	if maxC > 0 {
+		saturation = delta / maxC * 100
+	}
+	return domain.ColorState{Hue: hue, Saturation: saturation, Value: maxC * 100}
+}
+
+func hsvToRGB(c domain.ColorState) domain.RGB {
+	s, v := c.Saturation/100, c.Value/100
+	chroma := v * s
+	x := chroma * (1 - math.Abs(math.Mod(c.Hue/60, 2)-1))
+	m := v - chroma
+
+	var r, g, b float64
+	switch {
+	case c.Hue < 60:
+		r, g = chroma, x
+	case c.Hue < 120:
+		r, g = x, chroma
+	case c.Hue < 180:
+		g, b = chroma, x
+	case c.Hue < 240:
+		g, b = x, chroma
+	case c.Hue < 300:
+		r, b = x, chroma
+	default:
+		r, b = chroma, x
+	}
+
+	channel := func(f float64) int { return int(math.Round((f + m) * 255)) }
+	return domain.RGB{R: channel(r), G: channel(g), B: channel(b)}
+}
+
+// isLegacyColor reports whether a colour_data value uses the hex form some
+// older v1 lights report instead of JSON: rrggbb followed by a four-digit hue
+// and two-digit saturation and value.
+func isLegacyColor(s string) bool {
+	if len(s) != 14 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// decodeColor reads a colour_data value, which Tuya reports as a JSON string
+// or, on some older lights, in the legacy hex form.
+func decodeColor(value any, r colorRange) (domain.ColorState, error) {
+	s, ok := value.(string)
+	if !ok {
+		return domain.ColorState{}, fmt.Errorf("colour data is not a string")
+	}
+
+	if isLegacyColor(s) {
+		h, _ := strconv.ParseUint(s[6:10], 16, 16)
+		sat, _ := strconv.ParseUint(s[10:12], 16, 8)
+		val, _ := strconv.ParseUint(s[12:14], 16, 8)
+		return domain.ColorState{
+			Hue:        float64(h),
+			Saturation: math.Round(float64(sat)/255*1000) / 10,
+			Value:      math.Round(float64(val)/255*1000) / 10,
+		}, nil
+	}
+
+	var raw struct {
+		H float64 `json:"h"`
+		S float64 `json:"s"`
+		V float64 `json:"v"`
+	}
+	if err := json.Unmarshal([]byte(s), &raw); err != nil {
+		return domain.ColorState{}, err
+	}
+
+	return domain.ColorState{
+		Hue:        raw.H / r.H.Max * 360,
+		Saturation: r.S.toPercent(raw.S),
+		Value:      r.V.toPercent(raw.V),
+	}, nil
+}
+
+func encodeColor(state domain.ColorState, r colorRange, legacy bool) (string, error) {
+	if state.Hue < 0 || state.Hue > 360 || state.Saturation < 0 || state.Saturation > 100 || state.Value < 0 || state.Value > 100 {
+		return "", fmt.Errorf("%w: hue must be 0-360 and saturation and value 0-100", domain.ErrInvalidCapability)
+	}
+
+	if legacy {
+		rgb := hsvToRGB(state)
+		return fmt.Sprintf("%02x%02x%02x%04x%02x%02x", rgb.R, rgb.G, rgb.B,
+			int(math.Round(state.Hue)),
+			int(math.Round(state.Saturation/100*255)),
+			int(math.Round(state.Value/100*255))), nil
+	}
+
+	data, err := json.Marshal(map[string]int{
+		"h": int(math.Round(state.Hue / 360 * r.H.Max)),
+		"s": r.S.fromPercent(state.Saturation),
+		"v": r.V.fromPercent(state.Value),
+	})
+	return string(data), err
+}
@@ -0,0 +1,205 @@
+package capability
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/avagenc/zee-api/internal/domain"
+)
+
+func approxColor(a, b domain.ColorState) bool {
+	const tolerance = 0.5
+	return math.Abs(a.Hue-b.Hue) < tolerance &&
+		math.Abs(a.Saturation-b.Saturation) < tolerance &&
+		math.Abs(a.Value-b.Value) < tolerance
+}
+
+func TestRGBToHSV(t *testing.T) {
+	tests := []struct {
+		name string
+		rgb  domain.RGB
+		want domain.ColorState
+	}{
+		{name: "red", rgb: domain.RGB{R: 255}, want: domain.ColorState{Hue: 0, Saturation: 100, Value: 100}},
+		{name: "green", rgb: domain.RGB{G: 255}, want: domain.ColorState{Hue: 120, Saturation: 100, Value: 100}},
+		{name: "blue", rgb: domain.RGB{B: 255}, want: domain.ColorState{Hue: 240, Saturation: 100, Value: 100}},
+		{name: "magenta", rgb: domain.RGB{R: 255, B: 255}, want: domain.ColorState{Hue: 300, Saturation: 100, Value: 100}},
+		{name: "negative hue wraps", rgb: domain.RGB{R: 255, B: 128}, want: domain.ColorState{Hue: 329.9, Saturation: 100, Value: 100}},
+		{name: "white has zero saturation", rgb: domain.RGB{R: 255, G: 255, B: 255}, want: domain.ColorState{Saturation: 0, Value: 100}},
+		{name: "black", rgb: domain.RGB{}, want: domain.ColorState{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rgbToHSV(tt.rgb); !approxColor(got, tt.want) {
+				t.Errorf("rgbToHSV(%+v) = %+v, want %+v", tt.rgb, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHSVToRGB(t *testing.T) {
+	tests := []struct {
+		name  string
+		color domain.ColorState
+		want  domain.RGB
+	}{
+		{name: "red", color: domain.ColorState{Hue: 0, Saturation: 100, Value: 100}, want: domain.RGB{R: 255}},
+		{name: "hue 360 is red", color: domain.ColorState{Hue: 360, Saturation: 100, Value: 100}, want: domain.RGB{R: 255}},
+		{name: "cyan", color: domain.ColorState{Hue: 180, Saturation: 100, Value: 100}, want: domain.RGB{G: 255, B: 255}},
+		{name: "zero saturation is grey", color: domain.ColorState{Hue: 200, Saturation: 0, Value: 50}, want: domain.RGB{R: 128, G: 128, B: 128}},
+		{name: "zero value is black", color: domain.ColorState{Hue: 90, Saturation: 100, Value: 0}, want: domain.RGB{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hsvToRGB(tt.color); got != tt.want {
+				t.Errorf("hsvToRGB(%+v) = %+v, want %+v", tt.color, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRGBRoundTrip(t *testing.T) {
+	for _, rgb := range []domain.RGB{
+		{R: 255},
+		{R: 255, G: 128},
+		{R: 12, G: 200, B: 99},
+		{R: 64, G: 64, B: 64},
+		{R: 1, G: 2, B: 254},
+		{R: 255, G: 255, B: 255},
+	} {
+		if got := hsvToRGB(rgbToHSV(rgb)); got != rgb {
+			t.Errorf("hsvToRGB(rgbToHSV(%+v)) = %+v", rgb, got)
+		}
+	}
+}
+
+func TestEncodeColor(t *testing.T) {
+	v2 := defaultColorRanges["colour_data_v2"]
+	v1 := defaultColorRanges["colour_data"]
+
+	tests := []struct {
+		name    string
+		color   domain.ColorState
+		r       colorRange
+		legacy  bool
+		want    string
+		wantErr error
+	}{
+		{name: "v2 json", color: domain.ColorState{Hue: 120, Saturation: 50, Value: 100}, r: v2, want: `{"h":120,"s":500,"v":1000}`},
+		{name: "v1 json", color: domain.ColorState{Hue: 240, Saturation: 100, Value: 50}, r: v1, want: `{"h":240,"s":255,"v":128}`},
+		{name: "hue 360", color: domain.ColorState{Hue: 360, Saturation: 100, Value: 100}, r: v2, want: `{"h":360,"s":1000,"v":1000}`},
+		{name: "zero saturation", color: domain.ColorState{Hue: 45, Saturation: 0, Value: 100}, r: v2, want: `{"h":45,"s":0,"v":1000}`},
+		{name: "legacy hex", color: domain.ColorState{Hue: 0, Saturation: 100, Value: 100}, r: v1, legacy: true, want: "ff00000000ffff"},
+		{name: "legacy hex hue 360", color: domain.ColorState{Hue: 360, Saturation: 100, Value: 100}, r: v1, legacy: true, want: "ff00000168ffff"},
+		{name: "legacy hex zero saturation", color: domain.ColorState{Hue: 200, Saturation: 0, Value: 50}, r: v1, legacy: true, want: "80808000c80080"},
+		{name: "hue out of range", color: domain.ColorState{Hue: 361, Saturation: 100, Value: 100}, r: v2, wantErr: domain.ErrInvalidCapability},
+		{name: "saturation out of range", color: domain.ColorState{Hue: 0, Saturation: -1, Value: 100}, r: v2, wantErr: domain.ErrInvalidCapability},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encodeColor(tt.color, tt.r, tt.legacy)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("encodeColor() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("encodeColor() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeColor(t *testing.T) {
+	v2 := defaultColorRanges["colour_data_v2"]
+	v1 := defaultColorRanges["colour_data"]
+
+	tests := []struct {
+		name    string
+		value   any
+		r       colorRange
+		want    domain.ColorState
+		wantErr bool
+	}{
+		{name: "v2 json", value: `{"h":120,"s":500,"v":1000}`, r: v2, want: domain.ColorState{Hue: 120, Saturation: 50, Value: 100}},
+		{name: "v1 json", value: `{"h":240,"s":255,"v":128}`, r: v1, want: domain.ColorState{Hue: 240, Saturation: 100, Value: 50.2}},
+		{name: "legacy hex", value: "ff00000000ffff", r: v1, want: domain.ColorState{Hue: 0, Saturation: 100, Value: 100}},
+		{name: "legacy hex hue 360", value: "ff00000168ffff", r: v1, want: domain.ColorState{Hue: 360, Saturation: 100, Value: 100}},
+		{name: "legacy hex zero saturation", value: "80808000c80080", r: v1, want: domain.ColorState{Hue: 200, Saturation: 0, Value: 50.2}},
+		{name: "not a string", value: 42.0, r: v2, wantErr: true},
+		{name: "malformed json", value: `{"h":`, r: v2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeColor(tt.value, tt.r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeColor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !approxColor(got, tt.want) {
+				t.Errorf("decodeColor() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColorRoundTrip(t *testing.T) {
+	colors := []domain.ColorState{
+		{Hue: 0, Saturation: 100, Value: 100},
+		{Hue: 75, Saturation: 40, Value: 60},
+		{Hue: 359, Saturation: 10, Value: 90},
+		{Hue: 210, Saturation: 0, Value: 30},
+	}
+
+	tests := []struct {
+		name   string
+		r      colorRange
+		legacy bool
+	}{
+		{name: "v2", r: defaultColorRanges["colour_data_v2"]},
+		{name: "v1", r: defaultColorRanges["colour_data"]},
+		{name: "legacy hex", r: defaultColorRanges["colour_data"], legacy: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, color := range colors {
+				encoded, err := encodeColor(color, tt.r, tt.legacy)
+				if err != nil {
+					t.Fatalf("encodeColor(%+v): %v", color, err)
+				}
+				if isLegacyColor(encoded) != tt.legacy {
+					t.Errorf("isLegacyColor(%s) = %v, want %v", encoded, !tt.legacy, tt.legacy)
+				}
+				decoded, err := decodeColor(encoded, tt.r)
+				if err != nil {
+					t.Fatalf("decodeColor(%s): %v", encoded, err)
+				}
+				if !approxColor(decoded, color) {
+					t.Errorf("round trip of %+v through %s = %+v", color, encoded, decoded)
+				}
+			}
+		})
+	}
+}
+
+func TestKelvinToPercent(t *testing.T) {
+	tests := []struct {
+		kelvin float64
+		want   float64
+	}{
+		{kelvin: warmestKelvin, want: 0},
+		{kelvin: coolestKelvin, want: 100},
+		{kelvin: 4600, want: 50},
+		{kelvin: 1800, want: 0},
+		{kelvin: 9000, want: 100},
+	}
+
+	for _, tt := range tests {
+		if got := kelvinToPercent(tt.kelvin); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("kelvinToPercent(%v) = %v, want %v", tt.kelvin, got, tt.want)
+		}
+	}
+}
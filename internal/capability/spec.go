@@ -0,0 +1,133 @@
+package capability
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/avagenc/zee-api/internal/domain"
+)
+
+// integerRange is the span of an Integer data point. Raw values lie within
+// [Min, Max] and carry Scale decimal places, so 215 with scale 1 is 21.5.
+type integerRange struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Scale int     `json:"scale"`
+}
+
+func (r integerRange) toPercent(raw float64) float64 {
+	if r.Max <= r.Min {
+		return 0
+	}
+	return math.Round((raw-r.Min)/(r.Max-r.Min)*1000) / 10
+}
+
+func (r integerRange) fromPercent(percent float64) int {
+	return int(math.Round(r.Min + percent/100*(r.Max-r.Min)))
+}
+
+func (r integerRange) toValue(raw float64) float64 {
+	return raw / math.Pow10(r.Scale)
+}
+
+func (r integerRange) fromValue(value float64) int {
+	return int(math.Round(value * math.Pow10(r.Scale)))
+}
+
+func (r integerRange) contains(raw int) bool {
+	return float64(raw) >= r.Min && float64(raw) <= r.Max
+}
+
+// colorRange is the span of each component of a colour_data data point.
+type colorRange struct {
+	H integerRange `json:"h"`
+	S integerRange `json:"s"`
+	V integerRange `json:"v"`
+}
+
+// defaultRanges are the ranges Tuya's standard instruction sets use, applied
+// when a device's specification is unavailable or omits the data point.
+var defaultRanges = map[string]integerRange{
+	"bright_value_v2": {Min: 10, Max: 1000},
+	"bright_value":    {Min: 25, Max: 255},
+	"temp_value_v2":   {Min: 0, Max: 1000},
+	"temp_value":      {Min: 0, Max: 255},
+	"temp_set":        {Min: 5, Max: 35},
+	"temp_current":    {Min: -20, Max: 50},
+	"percent_control": {Min: 0, Max: 100},
+	"percent_state":   {Min: 0, Max: 100},
+	"cur_power":       {Min: 0, Max: 99999, Scale: 1},
+	"cur_voltage":     {Min: 0, Max: 5000, Scale: 1},
+	"cur_current":     {Min: 0, Max: 30000},
+}
+
+var defaultColorRanges = map[string]colorRange{
+	"colour_data_v2": {H: integerRange{Max: 360}, S: integerRange{Max: 1000}, V: integerRange{Max: 1000}},
+	"colour_data":    {H: integerRange{Max: 360}, S: integerRange{Max: 255}, V: integerRange{Max: 255}},
+}
+
+// Spec resolves data point ranges and enum members from a device
+// specification, falling back to the standard ranges.
+type Spec struct {
+	ranges      map[string]integerRange
+	colorRanges map[string]colorRange
+	enums       map[string][]string
+}
+
+// NewSpec reads the ranges of a device specification. A nil specification
+// yields the standard ranges only.
+func NewSpec(spec *domain.DeviceSpec) Spec {
+	s := Spec{
+		ranges:      make(map[string]integerRange),
+		colorRanges: make(map[string]colorRange),
+		enums:       make(map[string][]string),
+	}
+	if spec == nil {
+		return s
+	}
+
+	// Functions describe what can be set and take precedence over the
+	// reported status ranges.
+	points := append(append([]domain.DataPointSpec{}, spec.Status...), spec.Functions...)
+	for _, dp := range points {
+		switch dp.Type {
+		case "Integer":
+			var r integerRange
+			if json.Unmarshal([]byte(dp.Values), &r) == nil && r.Max > r.Min {
+				s.ranges[dp.Code] = r
+			}
+		case "Json":
+			var r colorRange
+			if json.Unmarshal([]byte(dp.Values), &r) == nil && r.H.Max > 0 && r.S.Max > 0 && r.V.Max > 0 {
+				s.colorRanges[dp.Code] = r
+			}
+		case "Enum":
+			var e struct {
+				Range []string `json:"range"`
+			}
+			if json.Unmarshal([]byte(dp.Values), &e) == nil && len(e.Range) > 0 {
+				s.enums[dp.Code] = e.Range
+			}
+		}
+	}
+	return s
+}
+
+func (s Spec) integer(code string) integerRange {
+	if r, ok := s.ranges[code]; ok {
+		return r
+	}
+	return defaultRanges[code]
+}
+
+func (s Spec) color(code string) colorRange {
+	if r, ok := s.colorRanges[code]; ok {
+		return r
+	}
+	return defaultColorRanges[code]
+}
+
+// enum returns the members of an Enum data point, or nil when unknown.
+func (s Spec) enum(code string) []string {
+	return s.enums[code]
+}
@@ -0,0 +1,43 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/pkg/api"
+	"github.com/go-chi/chi/v5"
+)
+
+type Service interface {
+	Get(ctx context.Context, userID string, commandID string) (domain.Command, error)
+}
+
+type Handler struct {
+	svc Service
+}
+
+func NewHandler(svc Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	cmd, err := h.svc.Get(r.Context(), userID, chi.URLParam(r, "commandId"))
+	if err != nil {
+		if errors.Is(err, domain.ErrCommandNotFound) {
+			api.Respond(w, http.StatusNotFound, api.NewErrorResponse("NOT_FOUND", "Command not found", nil))
+			return
+		}
+		api.Respond(w, http.StatusInternalServerError, api.NewErrorResponse("INTERNAL_ERROR", err.Error(), nil))
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Command retrieved successfully", cmd, nil))
+}
@@ -0,0 +1,183 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const invalidTextRepresentation = "22P02"
+
+// errLeaseLost reports that a sending command was settled by ExpireStale
+// before the worker that claimed it recorded the outcome.
+var errLeaseLost = errors.New("command is no longer sending; its lease expired")
+
+// tenantScope matches rows of the tenant in the given placeholder, where an
+// empty tenant ID selects the deployment's default project.
+const tenantScope = `tenant_id IS NOT DISTINCT FROM NULLIF(%s, '')::uuid`
+
+const commandColumns = `id, owner_id, COALESCE(tenant_id::text, ''), COALESCE(region, ''), COALESCE(tuya_uid, ''), device_id, commands, state, result, COALESCE(error, ''), attempts, created_at, updated_at, sent_at, confirmed_at`
+
+type repository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRepository(pool *pgxpool.Pool) *repository {
+	return &repository{pool: pool}
+}
+
+func (r *repository) Enqueue(ctx context.Context, cmd domain.Command) (domain.Command, error) {
+	commands, err := json.Marshal(cmd.Commands)
+	if err != nil {
+		return domain.Command{}, fmt.Errorf("failed to marshal commands: %w", err)
+	}
+
+	query := `INSERT INTO device_commands (tenant_id, region, owner_id, tuya_uid, device_id, commands) VALUES (NULLIF($1, '')::uuid, NULLIF($2, ''), $3, $4, $5, $6) RETURNING ` + commandColumns
+	return scanCommand(r.pool.QueryRow(ctx, query, cmd.TenantID, cmd.Region, cmd.OwnerID, cmd.TuyaUID, cmd.DeviceID, commands))
+}
+
+func (r *repository) Get(ctx context.Context, tenantID, ownerID, commandID string) (domain.Command, error) {
+	query := `SELECT ` + commandColumns + ` FROM device_commands WHERE ` + fmt.Sprintf(tenantScope, "$1") + ` AND owner_id = $2 AND id = $3`
+
+	cmd, err := scanCommand(r.pool.QueryRow(ctx, query, tenantID, ownerID, commandID))
+	if err != nil {
+		if isNotFound(err) {
+			return domain.Command{}, domain.ErrCommandNotFound
+		}
+		return domain.Command{}, err
+	}
+	return cmd, nil
+}
+
+// ClaimQueued moves up to limit queued commands to sending and leases them
+// until available_at. A command is never claimed again once sending: if the
+// lease runs out without the send being recorded, ExpireStale settles it
+// rather than sending it to the device a second time.
+func (r *repository) ClaimQueued(ctx context.Context, limit int, lease time.Duration) ([]domain.Command, error) {
+	query := `
+		UPDATE device_commands SET state = 'sending', attempts = attempts + 1, available_at = NOW() + $2 * INTERVAL '1 millisecond', updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM device_commands
+			WHERE state = 'queued' AND available_at <= NOW()
+			ORDER BY available_at
+			FOR UPDATE SKIP LOCKED
+			LIMIT $1
+		)
+		RETURNING ` + commandColumns
+
+	return r.queryCommands(ctx, query, limit, lease.Milliseconds())
+}
+
+func (r *repository) ListAwaitingConfirmation(ctx context.Context, limit int) ([]domain.Command, error) {
+	query := `SELECT ` + commandColumns + ` FROM device_commands WHERE state = 'sent' AND confirm_deadline > NOW() ORDER BY sent_at LIMIT $1`
+	return r.queryCommands(ctx, query, limit)
+}
+
+// ExpireStale finalises commands that can no longer progress: sent commands
+// past their confirmation deadline, and sending commands whose lease ran out
+// before their outcome was recorded.
+func (r *repository) ExpireStale(ctx context.Context) (int64, error) {
+	query := `
+		UPDATE device_commands SET state = 'unconfirmed', updated_at = NOW(),
+			error = CASE state
+				WHEN 'sent' THEN 'device did not report the requested values before the confirmation deadline'
+				ELSE 'send outcome was not recorded; the command may have reached the device'
+			END
+		WHERE (state = 'sent' AND confirm_deadline <= NOW())
+		   OR (state = 'sending' AND available_at <= NOW())`
+
+	tag, err := r.pool.Exec(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// MarkSent, MarkFailed, MarkUnconfirmed and Requeue record the outcome of a
+// send. They only apply to commands still sending, so a worker whose lease
+// ran out cannot overwrite what ExpireStale settled; they report errLeaseLost
+// instead.
+func (r *repository) MarkSent(ctx context.Context, commandID string, result json.RawMessage, confirmDeadline time.Time) error {
+	query := `UPDATE device_commands SET state = 'sent', result = $2, sent_at = NOW(), confirm_deadline = $3, updated_at = NOW() WHERE id = $1 AND state = 'sending'`
+	return r.settle(ctx, query, commandID, result, confirmDeadline)
+}
+
+func (r *repository) MarkConfirmed(ctx context.Context, commandID string) error {
+	query := `UPDATE device_commands SET state = 'confirmed', confirmed_at = NOW(), updated_at = NOW() WHERE id = $1 AND state = 'sent'`
+	_, err := r.pool.Exec(ctx, query, commandID)
+	return err
+}
+
+func (r *repository) MarkFailed(ctx context.Context, commandID string, reason string) error {
+	query := `UPDATE device_commands SET state = 'failed', error = $2, updated_at = NOW() WHERE id = $1 AND state = 'sending'`
+	return r.settle(ctx, query, commandID, reason)
+}
+
+func (r *repository) MarkUnconfirmed(ctx context.Context, commandID string, reason string) error {
+	query := `UPDATE device_commands SET state = 'unconfirmed', error = $2, updated_at = NOW() WHERE id = $1 AND state = 'sending'`
+	return r.settle(ctx, query, commandID, reason)
+}
+
+func (r *repository) Requeue(ctx context.Context, commandID string, reason string, delay time.Duration) error {
+	query := `UPDATE device_commands SET state = 'queued', error = $2, available_at = NOW() + $3 * INTERVAL '1 millisecond', updated_at = NOW() WHERE id = $1 AND state = 'sending'`
+	return r.settle(ctx, query, commandID, reason, delay.Milliseconds())
+}
+
+func (r *repository) settle(ctx context.Context, query string, args ...any) error {
+	tag, err := r.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return errLeaseLost
+	}
+	return nil
+}
+
+func (r *repository) queryCommands(ctx context.Context, query string, args ...any) ([]domain.Command, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var commands []domain.Command
+	for rows.Next() {
+		cmd, err := scanCommand(rows)
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, cmd)
+	}
+	return commands, rows.Err()
+}
+
+func scanCommand(row pgx.Row) (domain.Command, error) {
+	var cmd domain.Command
+	var commands []byte
+	err := row.Scan(&cmd.ID, &cmd.OwnerID, &cmd.TenantID, &cmd.Region, &cmd.TuyaUID, &cmd.DeviceID, &commands, &cmd.State, &cmd.Result, &cmd.Error,
+		&cmd.Attempts, &cmd.CreatedAt, &cmd.UpdatedAt, &cmd.SentAt, &cmd.ConfirmedAt)
+	if err != nil {
+		return domain.Command{}, err
+	}
+
+	if err := json.Unmarshal(commands, &cmd.Commands); err != nil {
+		return domain.Command{}, fmt.Errorf("failed to unmarshal commands: %w", err)
+	}
+	return cmd, nil
+}
+
+func isNotFound(err error) bool {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == invalidTextRepresentation
+}
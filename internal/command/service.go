@@ -0,0 +1,39 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/pkg/api"
+)
+
+type Repository interface {
+	Enqueue(ctx context.Context, cmd domain.Command) (domain.Command, error)
+	Get(ctx context.Context, tenantID, ownerID, commandID string) (domain.Command, error)
+	ClaimQueued(ctx context.Context, limit int, lease time.Duration) ([]domain.Command, error)
+	ListAwaitingConfirmation(ctx context.Context, limit int) ([]domain.Command, error)
+	ExpireStale(ctx context.Context) (int64, error)
+	MarkSent(ctx context.Context, commandID string, result json.RawMessage, confirmDeadline time.Time) error
+	MarkConfirmed(ctx context.Context, commandID string) error
+	MarkFailed(ctx context.Context, commandID string, reason string) error
+	MarkUnconfirmed(ctx context.Context, commandID string, reason string) error
+	Requeue(ctx context.Context, commandID string, reason string, delay time.Duration) error
+}
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) *service {
+	return &service{repo: repo}
+}
+
+func (s *service) Enqueue(ctx context.Context, cmd domain.Command) (domain.Command, error) {
+	return s.repo.Enqueue(ctx, cmd)
+}
+
+func (s *service) Get(ctx context.Context, userID string, commandID string) (domain.Command, error) {
+	return s.repo.Get(ctx, api.GetTenantIDFromContext(ctx), userID, commandID)
+}
@@ -0,0 +1,180 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/internal/tuya"
+	"github.com/avagenc/zee-api/pkg/api"
+)
+
+const (
+	pollInterval   = time.Second
+	claimBatchSize = 10
+	maxAttempts    = 3
+	retryDelay     = 5 * time.Second
+
+	// A batch is sent one command at a time, each bounded by sendTimeout, so
+	// the lease covers the whole batch and the last command's outcome is
+	// recorded before ExpireStale can settle it.
+	sendTimeout = 5 * time.Second
+	claimLease  = claimBatchSize*sendTimeout + 10*time.Second
+
+	// Commands that are not confirmed within confirmTimeout end up
+	// unconfirmed: Tuya accepted them but the device never reported the
+	// values.
+	confirmTimeout = 30 * time.Second
+)
+
+type CommandAuditor func(ctx context.Context, entry domain.CommandAudit)
+
+type TuyaIoTClient interface {
+	SendCommands(ctx context.Context, deviceID string, commands any) (json.RawMessage, string, error)
+	GetStatus(ctx context.Context, deviceID string) ([]domain.DataPoint, error)
+}
+
+type Worker struct {
+	repo  Repository
+	tuya  TuyaIoTClient
+	audit CommandAuditor
+}
+
+func NewWorker(repo Repository, tuya TuyaIoTClient, audit CommandAuditor) *Worker {
+	return &Worker{repo: repo, tuya: tuya, audit: audit}
+}
+
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.dispatch(ctx)
+			w.confirm(ctx)
+			w.expire(ctx)
+		}
+	}
+}
+
+func (w *Worker) dispatch(ctx context.Context) {
+	commands, err := w.repo.ClaimQueued(ctx, claimBatchSize, claimLease)
+	if err != nil {
+		log.Printf("Warning: failed to claim queued commands: %v", err)
+		return
+	}
+
+	for _, cmd := range commands {
+		result, err := w.send(ctx, cmd)
+		if err != nil {
+			if err := w.settleFailure(ctx, cmd, err); err != nil {
+				log.Printf("Warning: failed to update command %s: %v", cmd.ID, err)
+			}
+			continue
+		}
+
+		// The command stays in sending if this fails, so it is not sent
+		// again and becomes unconfirmed once its lease expires.
+		if err := w.repo.MarkSent(ctx, cmd.ID, result, time.Now().Add(confirmTimeout)); err != nil {
+			log.Printf("Warning: command %s was sent but could not be marked as sent: %v", cmd.ID, err)
+		}
+	}
+}
+
+// settleFailure records a failed send. Only a command that never reached
+// Tuya, or that Tuya rejected, is retried; after any other failure Tuya may
+// have run it, and sending it again could repeat a toggle.
+func (w *Worker) settleFailure(ctx context.Context, cmd domain.Command, sendErr error) error {
+	var tuyaErr *tuya.Error
+	retryable := errors.Is(sendErr, tuya.ErrNotSent) || errors.As(sendErr, &tuyaErr)
+
+	switch {
+	case !retryable:
+		return w.repo.MarkUnconfirmed(ctx, cmd.ID, "send outcome is unknown; the command may have reached the device: "+sendErr.Error())
+	case cmd.Attempts >= maxAttempts:
+		return w.repo.MarkFailed(ctx, cmd.ID, sendErr.Error())
+	default:
+		return w.repo.Requeue(ctx, cmd.ID, sendErr.Error(), retryDelay)
+	}
+}
+
+func (w *Worker) send(ctx context.Context, cmd domain.Command) (json.RawMessage, error) {
+	ctx = commandContext(ctx, cmd)
+
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	start := time.Now()
+	result, tid, err := w.tuya.SendCommands(sendCtx, cmd.DeviceID, cmd.Commands)
+
+	entry := domain.CommandAudit{
+		OwnerID:   cmd.OwnerID,
+		TuyaUID:   cmd.TuyaUID,
+		DeviceID:  cmd.DeviceID,
+		Commands:  cmd.Commands,
+		Source:    domain.CommandSourceAsync,
+		RequestID: cmd.ID,
+		Success:   err == nil,
+		Result:    result,
+		Tid:       tid,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	w.audit(ctx, entry)
+
+	return result, err
+}
+
+func (w *Worker) confirm(ctx context.Context) {
+	commands, err := w.repo.ListAwaitingConfirmation(ctx, claimBatchSize)
+	if err != nil {
+		log.Printf("Warning: failed to list commands awaiting confirmation: %v", err)
+		return
+	}
+
+	for _, cmd := range commands {
+		status, err := w.tuya.GetStatus(commandContext(ctx, cmd), cmd.DeviceID)
+		if err != nil {
+			log.Printf("Warning: failed to read status for command %s: %v", cmd.ID, err)
+			continue
+		}
+
+		if !allMatched(domain.MatchDataPoints(cmd.Commands, status)) {
+			continue
+		}
+
+		if err := w.repo.MarkConfirmed(ctx, cmd.ID); err != nil {
+			log.Printf("Warning: failed to mark command %s as confirmed: %v", cmd.ID, err)
+		}
+	}
+}
+
+func (w *Worker) expire(ctx context.Context) {
+	if _, err := w.repo.ExpireStale(ctx); err != nil {
+		log.Printf("Warning: failed to expire stale commands: %v", err)
+	}
+}
+
+// commandContext routes Tuya calls for a queued command to the tenant and
+// region it was submitted under.
+func commandContext(ctx context.Context, cmd domain.Command) context.Context {
+	ctx = api.NewContextWithTenantID(ctx, cmd.TenantID)
+	return api.NewContextWithRegion(ctx, cmd.Region)
+}
+
+func allMatched(matches map[string]bool) bool {
+	for _, ok := range matches {
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
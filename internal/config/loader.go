@@ -25,8 +25,24 @@ func Load() (*Config, error) {
 			WriteTimeout: 10 * time.Second,
 			IdleTimeout:  120 * time.Second,
 		},
-		Security: &Security{},
-		Tuya:     &Tuya{},
+		Security: &Security{
+			IdempotencyTTL: 24 * time.Hour,
+		},
+		Auth: &Auth{
+			Mode:         AuthModeHeader,
+			JWTUserClaim: "sub",
+			JWTLeeway:    30 * time.Second,
+		},
+		RateLimit: &RateLimit{
+			Window:          time.Minute,
+			ReadsPerKey:     600,
+			ReadsPerUser:    120,
+			CommandsPerKey:  300,
+			CommandsPerUser: 30,
+		},
+		Tuya: &Tuya{
+			TokenStore: TuyaTokenStoreMemory,
+		},
 		Database: &Database{
 			MaxConns:        20,
 			MinConns:        0,
@@ -47,10 +63,30 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to load security config: %w", err)
 	}
 
+	if err := cleanenv.ReadEnv(cfg.Auth); err != nil {
+		return nil, fmt.Errorf("failed to load auth config: %w", err)
+	}
+
+	switch cfg.Auth.Mode {
+	case AuthModeHeader, AuthModeJWT, AuthModeHybrid:
+	default:
+		return nil, fmt.Errorf("invalid AUTH_MODE %q: expected %s, %s or %s", cfg.Auth.Mode, AuthModeHeader, AuthModeJWT, AuthModeHybrid)
+	}
+
+	if err := cleanenv.ReadEnv(cfg.RateLimit); err != nil {
+		return nil, fmt.Errorf("failed to load rate limit config: %w", err)
+	}
+
 	if err := cleanenv.ReadEnv(cfg.Tuya); err != nil {
 		return nil, fmt.Errorf("failed to load tuya config: %w", err)
 	}
 
+	switch cfg.Tuya.TokenStore {
+	case TuyaTokenStoreMemory, TuyaTokenStorePostgres:
+	default:
+		return nil, fmt.Errorf("invalid TUYA_TOKEN_STORE %q: expected %s or %s", cfg.Tuya.TokenStore, TuyaTokenStoreMemory, TuyaTokenStorePostgres)
+	}
+
 	if err := cleanenv.ReadEnv(cfg.Database); err != nil {
 		return nil, fmt.Errorf("failed to load database config: %w", err)
 	}
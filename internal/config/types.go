@@ -3,11 +3,13 @@ package config
 import "time"
 
 type Config struct {
-	App      *App
-	Server   *Server
-	Security *Security
-	Tuya     *Tuya
-	Database *Database
+	App       *App
+	Server    *Server
+	Security  *Security
+	Auth      *Auth
+	RateLimit *RateLimit
+	Tuya      *Tuya
+	Database  *Database
 }
 
 type App struct {
@@ -24,13 +26,49 @@ type Server struct {
 }
 
 type Security struct {
-	APIKey string `env:"API_KEY" env-required:"true"`
+	APIKey         string        `env:"API_KEY"`
+	IdempotencyTTL time.Duration `env:"IDEMPOTENCY_TTL"`
+	SecretKey      string        `env:"SECRET_KEY"`
+	SecretKeyID    string        `env:"SECRET_KEY_ID"`
+	SecretKeyFile  string        `env:"SECRET_KEY_FILE"`
+	SecretIndexKey string        `env:"SECRET_INDEX_KEY"`
 }
 
+const (
+	AuthModeHeader = "header"
+	AuthModeJWT    = "jwt"
+	AuthModeHybrid = "hybrid"
+)
+
+type Auth struct {
+	Mode             string        `env:"AUTH_MODE"`
+	JWTJWKSFile      string        `env:"JWT_JWKS_FILE"`
+	JWTPublicKeyFile string        `env:"JWT_PUBLIC_KEY_FILE"`
+	JWTHMACSecret    string        `env:"JWT_HMAC_SECRET"`
+	JWTIssuer        string        `env:"JWT_ISSUER"`
+	JWTAudience      string        `env:"JWT_AUDIENCE"`
+	JWTUserClaim     string        `env:"JWT_USER_CLAIM"`
+	JWTLeeway        time.Duration `env:"JWT_LEEWAY"`
+}
+
+type RateLimit struct {
+	Window          time.Duration `env:"RATE_LIMIT_WINDOW"`
+	ReadsPerKey     int           `env:"RATE_LIMIT_READS_PER_KEY"`
+	ReadsPerUser    int           `env:"RATE_LIMIT_READS_PER_USER"`
+	CommandsPerKey  int           `env:"RATE_LIMIT_COMMANDS_PER_KEY"`
+	CommandsPerUser int           `env:"RATE_LIMIT_COMMANDS_PER_USER"`
+}
+
+const (
+	TuyaTokenStoreMemory   = "memory"
+	TuyaTokenStorePostgres = "postgres"
+)
+
 type Tuya struct {
 	AccessID     string `env:"TUYA_ACCESS_ID" env-required:"true"`
 	AccessSecret string `env:"TUYA_ACCESS_SECRET" env-required:"true"`
 	BaseURL      string `env:"TUYA_BASE_URL" env-required:"true"`
+	TokenStore   string `env:"TUYA_TOKEN_STORE"`
 }
 
 type Database struct {
@@ -0,0 +1,289 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/pkg/api"
+)
+
+// accessResolver decides the user's role on individual devices by looking
+// each device up, so checking one device does not walk every account's
+// inventory. It caches what it loads for the lifetime of a request and is
+// safe for concurrent use.
+type accessResolver struct {
+	s        *service
+	accounts []domain.TuyaAccount
+	// grants are the grants the user received, by the user who granted them.
+	grants map[string][]domain.AccessGrant
+
+	mu      sync.Mutex
+	owners  map[string][]domain.TuyaAccount
+	devices map[string]deviceLookup
+	homes   map[string][]string
+}
+
+type deviceLookup struct {
+	device domain.Device
+	err    error
+}
+
+// deviceSource is an account whose devices are visible to the user. Grants is
+// nil for the user's own accounts and holds what the owner shared otherwise.
+type deviceSource struct {
+	account domain.TuyaAccount
+	grants  []domain.AccessGrant
+}
+
+func (s *service) newAccessResolver(ctx context.Context, userID string) (*accessResolver, error) {
+	linked := true
+	accounts, err := s.listAccounts(ctx, userID)
+	switch {
+	case errors.Is(err, domain.ErrAccountNotLinked):
+		linked = false
+	case err != nil:
+		return nil, err
+	}
+
+	grants, err := s.listGrants(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load shared devices: %w", err)
+	}
+
+	if !linked && len(grants) == 0 {
+		return nil, domain.ErrAccountNotLinked
+	}
+
+	byOwner := make(map[string][]domain.AccessGrant)
+	for _, g := range grants {
+		byOwner[g.OwnerID] = append(byOwner[g.OwnerID], g)
+	}
+
+	return &accessResolver{
+		s:        s,
+		accounts: accounts,
+		grants:   byOwner,
+		owners:   make(map[string][]domain.TuyaAccount),
+		devices:  make(map[string]deviceLookup),
+		homes:    make(map[string][]string),
+	}, nil
+}
+
+// verify checks that the user holds at least role on deviceID.
+func (r *accessResolver) verify(ctx context.Context, deviceID string, role string) (accessibleDevice, error) {
+	target, err := r.resolve(ctx, deviceID)
+	if err != nil {
+		return accessibleDevice{}, err
+	}
+	if !domain.RoleAllows(target.device.Role, role) {
+		return accessibleDevice{}, domain.ErrInsufficientRole
+	}
+	return target, nil
+}
+
+// resolve returns the device tagged with the user's role and the account it
+// belongs to. Devices of the user's own accounts take precedence over shared
+// ones; a device shared by several grants gets the highest role among them.
+func (r *accessResolver) resolve(ctx context.Context, deviceID string) (accessibleDevice, error) {
+	for _, acc := range r.accounts {
+		d, err := r.lookup(ctx, acc.Region, deviceID)
+		if errors.Is(err, domain.ErrDeviceNotOwned) {
+			continue
+		}
+		if err != nil {
+			return accessibleDevice{}, fmt.Errorf("failed to look up device %s: %w", deviceID, err)
+		}
+
+		owned, err := r.belongsTo(ctx, acc, d)
+		if err != nil {
+			return accessibleDevice{}, fmt.Errorf("failed to list homes of account %q: %w", acc.Label, err)
+		}
+		if owned {
+			return ownedDevice(d, acc), nil
+		}
+	}
+
+	var shared accessibleDevice
+	for _, src := range r.sharedSources(ctx) {
+		d, err := r.lookup(ctx, src.account.Region, deviceID)
+		if errors.Is(err, domain.ErrDeviceNotOwned) {
+			continue
+		}
+		if err != nil {
+			fmt.Printf("Warning: failed to look up shared device %s: %v\n", deviceID, err)
+			continue
+		}
+
+		owned, err := r.belongsTo(ctx, src.account, d)
+		if err != nil {
+			fmt.Printf("Warning: failed to list homes of sharing owner %s: %v\n", src.account.OwnerID, err)
+			continue
+		}
+		if !owned {
+			continue
+		}
+
+		role := sharedRole(d, src.grants)
+		if role == "" {
+			continue
+		}
+		if shared.device.ID == "" {
+			shared = sharedDevice(d, src.account, role)
+			continue
+		}
+		shared.device.Role = domain.HigherRole(shared.device.Role, role)
+	}
+
+	if shared.device.ID == "" {
+		return accessibleDevice{}, domain.ErrDeviceNotOwned
+	}
+	return shared, nil
+}
+
+// sources returns the user's accounts followed by the accounts of users who
+// shared devices with them, in a stable order so a list can be paged.
+func (r *accessResolver) sources(ctx context.Context) []deviceSource {
+	sources := make([]deviceSource, 0, len(r.accounts))
+	for _, acc := range r.accounts {
+		sources = append(sources, deviceSource{account: acc})
+	}
+	return append(sources, r.sharedSources(ctx)...)
+}
+
+func (r *accessResolver) sharedSources(ctx context.Context) []deviceSource {
+	ownerIDs := make([]string, 0, len(r.grants))
+	for ownerID := range r.grants {
+		ownerIDs = append(ownerIDs, ownerID)
+	}
+	sort.Strings(ownerIDs)
+
+	var sources []deviceSource
+	for _, ownerID := range ownerIDs {
+		for _, acc := range r.ownerAccounts(ctx, ownerID) {
+			sources = append(sources, deviceSource{account: acc, grants: r.grants[ownerID]})
+		}
+	}
+	return sources
+}
+
+// include tags a device listed from src with the user's role, reporting false
+// when the user cannot see it there: a shared device no grant covers, a device
+// the user owns through one of their own accounts, or, in the user's own
+// accounts, a device that another of those accounts owns and lists itself.
+func (r *accessResolver) include(d domain.Device, src deviceSource) (domain.Device, bool) {
+	ownsElsewhere := slices.ContainsFunc(r.accounts, func(acc domain.TuyaAccount) bool {
+		return acc.TuyaUID == d.UID && acc.ID != src.account.ID
+	})
+
+	if src.grants == nil {
+		if d.UID != src.account.TuyaUID && ownsElsewhere {
+			return domain.Device{}, false
+		}
+		return ownedDevice(d, src.account).device, true
+	}
+
+	if ownsElsewhere {
+		return domain.Device{}, false
+	}
+	role := sharedRole(d, src.grants)
+	if role == "" {
+		return domain.Device{}, false
+	}
+	return sharedDevice(d, src.account, role).device, true
+}
+
+func (r *accessResolver) ownerAccounts(ctx context.Context, ownerID string) []domain.TuyaAccount {
+	r.mu.Lock()
+	accounts, ok := r.owners[ownerID]
+	r.mu.Unlock()
+	if ok {
+		return accounts
+	}
+
+	accounts, err := r.s.listAccounts(ctx, ownerID)
+	if err != nil {
+		fmt.Printf("Warning: failed to resolve Tuya accounts for sharing owner %s: %v\n", ownerID, err)
+	}
+
+	r.mu.Lock()
+	r.owners[ownerID] = accounts
+	r.mu.Unlock()
+	return accounts
+}
+
+// lookup fetches a device from the data center of region. Tuya keeps each
+// region's devices apart, so a device is only found in its own region.
+func (r *accessResolver) lookup(ctx context.Context, region, deviceID string) (domain.Device, error) {
+	key := region + "/" + deviceID
+
+	r.mu.Lock()
+	cached, ok := r.devices[key]
+	r.mu.Unlock()
+	if ok {
+		return cached.device, cached.err
+	}
+
+	device, err := r.s.tuya.Get(api.NewContextWithRegion(ctx, region), deviceID)
+
+	r.mu.Lock()
+	r.devices[key] = deviceLookup{device: device, err: err}
+	r.mu.Unlock()
+	return device, err
+}
+
+// belongsTo reports whether acc can reach the device: it owns the device or
+// is a member of the home the device is in.
+func (r *accessResolver) belongsTo(ctx context.Context, acc domain.TuyaAccount, d domain.Device) (bool, error) {
+	if d.UID == acc.TuyaUID {
+		return true, nil
+	}
+	if d.HomeID == "" {
+		return false, nil
+	}
+
+	r.mu.Lock()
+	homes, ok := r.homes[acc.ID]
+	r.mu.Unlock()
+	if !ok {
+		var err error
+		if homes, err = r.s.tuya.ListHomeIDs(api.NewContextWithRegion(ctx, acc.Region), acc.TuyaUID); err != nil {
+			return false, err
+		}
+		r.mu.Lock()
+		r.homes[acc.ID] = homes
+		r.mu.Unlock()
+	}
+
+	return slices.Contains(homes, d.HomeID), nil
+}
+
+func ownedDevice(d domain.Device, acc domain.TuyaAccount) accessibleDevice {
+	d.Role = domain.RoleOwner
+	d.AccountID = acc.ID
+	d.AccountLabel = acc.Label
+	d.Region = acc.Region
+	return accessibleDevice{device: d, tuyaUID: acc.TuyaUID}
+}
+
+func sharedDevice(d domain.Device, acc domain.TuyaAccount, role string) accessibleDevice {
+	d.Role = role
+	d.Region = acc.Region
+	return accessibleDevice{device: d, tuyaUID: acc.TuyaUID}
+}
+
+// sharedRole returns the highest role the grants give on the device, or ""
+// when none covers it.
+func sharedRole(d domain.Device, grants []domain.AccessGrant) string {
+	role := ""
+	for _, g := range grants {
+		if g.Covers(d) {
+			role = domain.HigherRole(role, g.Role)
+		}
+	}
+	return role
+}
@@ -0,0 +1,115 @@
+package device
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/avagenc/zee-api/internal/capability"
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/pkg/api"
+)
+
+type deviceSpec struct {
+	spec      domain.DeviceSpec
+	fetchedAt time.Time
+}
+
+// SendCapabilities translates capability commands into the device's data
+// points, using its current status to pick codes, and sends them. It returns
+// the data points that were sent alongside Tuya's result.
+func (s *service) SendCapabilities(ctx context.Context, userID string, deviceID string, commands []domain.CapabilityCommand) ([]domain.DataPoint, json.RawMessage, error) {
+	return s.translateAndSend(ctx, userID, deviceID, func(status []domain.DataPoint, spec capability.Spec) ([]domain.DataPoint, error) {
+		return capability.Translate(status, spec, commands)
+	})
+}
+
+// SendLight sets a light's power, brightness, color or white temperature,
+// encoding them for the instruction set the light reports.
+func (s *service) SendLight(ctx context.Context, userID string, deviceID string, cmd domain.LightCommand) ([]domain.DataPoint, json.RawMessage, error) {
+	return s.translateAndSend(ctx, userID, deviceID, func(status []domain.DataPoint, spec capability.Spec) ([]domain.DataPoint, error) {
+		return capability.TranslateLight(status, spec, cmd)
+	})
+}
+
+func (s *service) translateAndSend(ctx context.Context, userID string, deviceID string, translate func(status []domain.DataPoint, spec capability.Spec) ([]domain.DataPoint, error)) ([]domain.DataPoint, json.RawMessage, error) {
+	target, err := s.verifyAccess(ctx, userID, deviceID, domain.RoleOperator)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx = target.context(ctx)
+
+	status := target.device.Status
+	if len(status) == 0 {
+		if status, err = s.tuya.GetStatus(ctx, deviceID); err != nil {
+			return nil, nil, fmt.Errorf("failed to read status for device %s: %w", deviceID, err)
+		}
+	}
+
+	points, err := translate(status, s.spec(ctx, deviceID))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, err := s.sendCommands(ctx, userID, target.tuyaUID, deviceID, points, domain.CommandSourceCapability)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to send commands: %w", err)
+	}
+	return points, result, nil
+}
+
+// deriveCapabilities fills in the capabilities of each device from its
+// reported status.
+func (s *service) deriveCapabilities(ctx context.Context, devices []domain.Device) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentCommands)
+
+	for i := range devices {
+		device := &devices[i]
+		device.Capabilities = []domain.Capability{}
+		if len(device.Status) == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func(device *domain.Device) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			spec := s.spec(api.NewContextWithRegion(ctx, device.Region), device.ID)
+			if caps := capability.Derive(device.Status, spec); caps != nil {
+				device.Capabilities = caps
+			}
+		}(device)
+	}
+
+	wg.Wait()
+}
+
+// spec returns the device's specification ranges. When Tuya cannot provide
+// them the standard ranges are used, which fit most devices.
+func (s *service) spec(ctx context.Context, deviceID string) capability.Spec {
+	now := time.Now()
+
+	s.specsMu.Lock()
+	cached, ok := s.specs[deviceID]
+	s.specsMu.Unlock()
+	if ok && now.Sub(cached.fetchedAt) < deviceSpecTTL {
+		return capability.NewSpec(&cached.spec)
+	}
+
+	spec, err := s.tuya.GetSpecification(ctx, deviceID)
+	if err != nil {
+		fmt.Printf("Warning: failed to get specification for device %s: %v\n", deviceID, err)
+		return capability.NewSpec(nil)
+	}
+
+	s.specsMu.Lock()
+	s.specs[deviceID] = deviceSpec{spec: spec, fetchedAt: now}
+	s.specsMu.Unlock()
+
+	return capability.NewSpec(&spec)
+}
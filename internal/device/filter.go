@@ -0,0 +1,175 @@
+package device
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/pkg/api"
+)
+
+func validateListQuery(query domain.DeviceListQuery) error {
+	switch strings.TrimPrefix(query.Sort, "-") {
+	case "", domain.DeviceSortName, domain.DeviceSortCategory:
+	default:
+		return fmt.Errorf("%w: cannot sort by %q", domain.ErrInvalidQuery, query.Sort)
+	}
+
+	for _, field := range query.Fields {
+		if !slices.Contains(domain.DeviceFields, field) {
+			return fmt.Errorf("%w: unknown field %q", domain.ErrInvalidQuery, field)
+		}
+	}
+	return nil
+}
+
+// filterDevices returns the devices matching filter in a new slice, leaving
+// the snapshot it was given untouched.
+func (s *service) filterDevices(ctx context.Context, devices []domain.Device, filter domain.DeviceFilter) ([]domain.Device, error) {
+	match := s.newDeviceMatcher(filter)
+	matched := make([]domain.Device, 0, len(devices))
+	for _, d := range devices {
+		ok, err := match.matches(ctx, d)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, d)
+		}
+	}
+	return matched, nil
+}
+
+// deviceMatcher tests devices against a filter. It lists the rooms of each
+// home once, as devices of that home are first seen.
+type deviceMatcher struct {
+	s      *service
+	filter domain.DeviceFilter
+	name   string
+	// rooms holds, by home ID, the IDs of the devices in the filtered room.
+	rooms map[string]map[string]bool
+}
+
+func (s *service) newDeviceMatcher(filter domain.DeviceFilter) *deviceMatcher {
+	return &deviceMatcher{
+		s:      s,
+		filter: filter,
+		name:   strings.ToLower(filter.Name),
+		rooms:  make(map[string]map[string]bool),
+	}
+}
+
+func (m *deviceMatcher) matches(ctx context.Context, d domain.Device) (bool, error) {
+	switch {
+	case m.filter.Category != "" && !strings.EqualFold(d.Category, m.filter.Category):
+		return false, nil
+	case m.filter.Online != nil && d.Online != *m.filter.Online:
+		return false, nil
+	case m.name != "" && !strings.Contains(strings.ToLower(d.Name), m.name):
+		return false, nil
+	case !reportsStatus(d, m.filter.Status):
+		return false, nil
+	case m.filter.Room == "":
+		return true, nil
+	case d.HomeID == "":
+		return false, nil
+	}
+
+	inRoom, ok := m.rooms[d.HomeID]
+	if !ok {
+		var err error
+		if inRoom, err = m.devicesInRoom(ctx, d.HomeID, d.Region); err != nil {
+			return false, err
+		}
+		m.rooms[d.HomeID] = inRoom
+	}
+	return inRoom[d.ID], nil
+}
+
+// devicesInRoom returns the IDs of devices of the home in rooms named as the
+// filtered room, or with that room ID.
+func (m *deviceMatcher) devicesInRoom(ctx context.Context, homeID, region string) (map[string]bool, error) {
+	rooms, err := m.s.tuya.ListRooms(api.NewContextWithRegion(ctx, region), homeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rooms of home %s: %w", homeID, err)
+	}
+
+	inRoom := make(map[string]bool)
+	for _, r := range rooms {
+		if r.ID != m.filter.Room && !strings.EqualFold(r.Name, m.filter.Room) {
+			continue
+		}
+		for _, id := range r.DeviceIDs {
+			inRoom[id] = true
+		}
+	}
+	return inRoom, nil
+}
+
+// reportsStatus reports whether the device reports every wanted data point.
+// Values are compared in their query-string form, so true matches "true" and
+// 25 matches "25".
+func reportsStatus(d domain.Device, wanted []domain.DataPoint) bool {
+	for _, w := range wanted {
+		if !slices.ContainsFunc(d.Status, func(dp domain.DataPoint) bool {
+			return dp.Code == w.Code && fmt.Sprint(dp.Value) == fmt.Sprint(w.Value)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortDevices orders devices in place by a DeviceSort key, keeping the
+// original order between equal keys. An empty key leaves the order as is.
+func sortDevices(devices []domain.Device, sort string) {
+	key := strings.TrimPrefix(sort, "-")
+	if key == "" {
+		return
+	}
+
+	value := func(d domain.Device) string { return strings.ToLower(d.Name) }
+	if key == domain.DeviceSortCategory {
+		value = func(d domain.Device) string { return strings.ToLower(d.Category) }
+	}
+
+	slices.SortStableFunc(devices, func(a, b domain.Device) int {
+		if strings.HasPrefix(sort, "-") {
+			return cmp.Compare(value(b), value(a))
+		}
+		return cmp.Compare(value(a), value(b))
+	})
+}
+
+func wantsField(fields []string, field string) bool {
+	return len(fields) == 0 || slices.Contains(fields, field)
+}
+
+// sparseDevices trims each device to the requested fields. The ID is always
+// kept so clients can page and address devices.
+func sparseDevices(devices []domain.Device, fields []string) ([]map[string]any, error) {
+	sparse := make([]map[string]any, len(devices))
+	for i, d := range devices {
+		data, err := json.Marshal(d)
+		if err != nil {
+			return nil, err
+		}
+
+		var full map[string]any
+		if err := json.Unmarshal(data, &full); err != nil {
+			return nil, err
+		}
+
+		sparse[i] = map[string]any{"id": d.ID}
+		for _, field := range fields {
+			if value, ok := full[field]; ok {
+				sparse[i][field] = value
+			}
+		}
+	}
+	return sparse, nil
+}
@@ -4,7 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/avagenc/zee-api/internal/domain"
 	"github.com/avagenc/zee-api/pkg/api"
@@ -12,10 +16,21 @@ import (
 )
 
 type Service interface {
-	List(ctx context.Context, userID string) ([]domain.Device, error)
+	List(ctx context.Context, userID string, query domain.DeviceListQuery) (domain.DevicePage, error)
+	Get(ctx context.Context, userID string, deviceID string) (domain.Device, error)
 	SendCommands(ctx context.Context, userID string, deviceID string, commands []domain.DataPoint) (json.RawMessage, error)
+	SendCommandsAndConfirm(ctx context.Context, userID string, deviceID string, commands []domain.DataPoint, timeout time.Duration) (domain.CommandConfirmation, error)
+	EnqueueCommands(ctx context.Context, userID string, deviceID string, commands []domain.DataPoint) (domain.Command, error)
+	SendCapabilities(ctx context.Context, userID string, deviceID string, commands []domain.CapabilityCommand) ([]domain.DataPoint, json.RawMessage, error)
+	SendLight(ctx context.Context, userID string, deviceID string, cmd domain.LightCommand) ([]domain.DataPoint, json.RawMessage, error)
+	SendBatchCommands(ctx context.Context, userID string, batch []domain.DeviceCommands) ([]domain.CommandResult, error)
+	GetLogs(ctx context.Context, userID string, deviceID string, query domain.DeviceLogQuery) (domain.DeviceLogPage, error)
+	Rename(ctx context.Context, userID string, deviceID string, name string) error
+	RenameChannel(ctx context.Context, userID string, deviceID string, identifier string, name string) error
 }
 
+const maxNameLength = 50
+
 type Handler struct {
 	svc Service
 }
@@ -31,17 +46,89 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	devices, err := h.svc.List(r.Context(), userID)
+	q := r.URL.Query()
+	query := domain.DeviceListQuery{
+		Cursor: q.Get("cursor"),
+		Sort:   q.Get("sort"),
+		Filter: domain.DeviceFilter{
+			Category: q.Get("category"),
+			Name:     strings.TrimSpace(q.Get("name")),
+			Room:     q.Get("room"),
+		},
+	}
+
+	if limit := q.Get("limit"); limit != "" {
+		query.Limit, err = strconv.Atoi(limit)
+		if err != nil || query.Limit <= 0 {
+			api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid limit", nil))
+			return
+		}
+	}
+
+	if online := q.Get("online"); online != "" {
+		value, err := strconv.ParseBool(online)
+		if err != nil {
+			api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid online, expected true or false", nil))
+			return
+		}
+		query.Filter.Online = &value
+	}
+
+	for key, values := range q {
+		if code, ok := strings.CutPrefix(key, "status."); ok && code != "" {
+			query.Filter.Status = append(query.Filter.Status, domain.DataPoint{Code: code, Value: values[0]})
+		}
+	}
+
+	if fields := q.Get("fields"); fields != "" {
+		for _, field := range strings.Split(fields, ",") {
+			query.Fields = append(query.Fields, strings.TrimSpace(field))
+		}
+	}
+
+	page, err := h.svc.List(r.Context(), userID, query)
 	if err != nil {
-		if errors.Is(err, domain.ErrAccountNotLinked) {
-			api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "No Tuya App Account is linked to the user", nil))
+		respondError(w, err)
+		return
+	}
+
+	meta := map[string]any{
+		"next_cursor": page.NextCursor,
+	}
+
+	if len(page.Fields) > 0 {
+		devices, err := sparseDevices(page.Devices, page.Fields)
+		if err != nil {
+			api.Respond(w, http.StatusInternalServerError, api.NewErrorResponse("INTERNAL_ERROR", err.Error(), nil))
 			return
 		}
-		api.Respond(w, http.StatusBadGateway, api.NewErrorResponse("UPSTREAM_ERROR", err.Error(), nil))
+		api.Respond(w, http.StatusOK, api.NewSuccessResponse("Devices retrieved successfully", devices, meta))
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Devices retrieved successfully", page.Devices, meta))
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	deviceID := chi.URLParam(r, "deviceId")
+	if deviceID == "" {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Missing deviceId", nil))
 		return
 	}
 
-	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Devices retrieved successfully", devices, nil))
+	device, err := h.svc.Get(r.Context(), userID, deviceID)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Device retrieved successfully", device, nil))
 }
 
 func (h *Handler) SendCommands(w http.ResponseWriter, r *http.Request) {
@@ -71,19 +158,337 @@ func (h *Handler) SendCommands(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if r.URL.Query().Get("async") == "true" {
+		cmd, err := h.svc.EnqueueCommands(r.Context(), userID, deviceID, req.Commands)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+
+		w.Header().Set("Location", "/commands/"+cmd.ID)
+		api.Respond(w, http.StatusAccepted, api.NewSuccessResponse("Commands accepted for processing", cmd, nil))
+		return
+	}
+
+	if r.URL.Query().Get("confirm") == "true" {
+		var timeout time.Duration
+		if raw := r.URL.Query().Get("timeout_ms"); raw != "" {
+			ms, err := strconv.Atoi(raw)
+			if err != nil || ms <= 0 {
+				api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid timeout_ms", nil))
+				return
+			}
+			timeout = time.Duration(ms) * time.Millisecond
+		}
+
+		confirmation, err := h.svc.SendCommandsAndConfirm(r.Context(), userID, deviceID, req.Commands, timeout)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+
+		message := "Commands sent and confirmed by device"
+		if !confirmation.Confirmed {
+			message = "Commands sent but not confirmed by device"
+		}
+		api.Respond(w, http.StatusOK, api.NewSuccessResponse(message, confirmation, nil))
+		return
+	}
+
 	result, err := h.svc.SendCommands(r.Context(), userID, deviceID, req.Commands)
 	if err != nil {
-		if errors.Is(err, domain.ErrAccountNotLinked) {
-			api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "No Tuya App Account is linked to the user", nil))
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Commands sent successfully", result, nil))
+}
+
+func (h *Handler) SendCapabilities(w http.ResponseWriter, r *http.Request) {
+	userID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	deviceID := chi.URLParam(r, "deviceId")
+	if deviceID == "" {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Missing deviceId", nil))
+		return
+	}
+
+	var req struct {
+		Capabilities []domain.CapabilityCommand `json:"capabilities"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid request body", nil))
+		return
+	}
+
+	if len(req.Capabilities) == 0 {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Capabilities cannot be empty", nil))
+		return
+	}
+
+	commands, result, err := h.svc.SendCapabilities(r.Context(), userID, deviceID, req.Capabilities)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	data := map[string]any{
+		"commands": commands,
+		"result":   result,
+	}
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Commands sent successfully", data, nil))
+}
+
+func (h *Handler) SendLight(w http.ResponseWriter, r *http.Request) {
+	userID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	deviceID := chi.URLParam(r, "deviceId")
+	if deviceID == "" {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Missing deviceId", nil))
+		return
+	}
+
+	var req domain.LightCommand
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid request body", nil))
+		return
+	}
+
+	commands, result, err := h.svc.SendLight(r.Context(), userID, deviceID, req)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	data := map[string]any{
+		"commands": commands,
+		"result":   result,
+	}
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Commands sent successfully", data, nil))
+}
+
+func (h *Handler) SendBatchCommands(w http.ResponseWriter, r *http.Request) {
+	userID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	var req struct {
+		Requests []domain.DeviceCommands `json:"requests"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid request body", nil))
+		return
+	}
+
+	if len(req.Requests) == 0 {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Requests cannot be empty", nil))
+		return
+	}
+
+	for i, entry := range req.Requests {
+		if entry.DeviceID == "" || len(entry.Commands) == 0 {
+			api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", fmt.Sprintf("Request %d must have a deviceId and commands", i), nil))
 			return
 		}
-		if errors.Is(err, domain.ErrDeviceNotOwned) {
-			api.Respond(w, http.StatusForbidden, api.NewErrorResponse("FORBIDDEN", "Device does not belong to user", nil))
+	}
+
+	results, err := h.svc.SendBatchCommands(r.Context(), userID, req.Requests)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	succeeded := 0
+	for _, result := range results {
+		if result.Success {
+			succeeded++
+		}
+	}
+
+	meta := map[string]int{
+		"total":     len(results),
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+	}
+
+	switch {
+	case succeeded == len(results):
+		api.Respond(w, http.StatusOK, api.NewSuccessResponse("Commands sent successfully", results, meta))
+	case succeeded == 0:
+		api.Respond(w, http.StatusMultiStatus, api.Response{Code: "MULTI_STATUS", Message: "Commands failed for every device", Data: results, Meta: meta})
+	default:
+		api.Respond(w, http.StatusMultiStatus, api.NewPartialSuccessResponse("Commands sent to some devices", results, meta))
+	}
+}
+
+func (h *Handler) Rename(w http.ResponseWriter, r *http.Request) {
+	userID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	deviceID := chi.URLParam(r, "deviceId")
+	if deviceID == "" {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Missing deviceId", nil))
+		return
+	}
+
+	name, ok := decodeName(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Rename(r.Context(), userID, deviceID, name); err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Device renamed successfully", map[string]any{
+		"id":   deviceID,
+		"name": name,
+	}, nil))
+}
+
+func (h *Handler) RenameChannel(w http.ResponseWriter, r *http.Request) {
+	userID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	deviceID := chi.URLParam(r, "deviceId")
+	identifier := chi.URLParam(r, "identifier")
+	if deviceID == "" || identifier == "" {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Missing deviceId or channel identifier", nil))
+		return
+	}
+
+	name, ok := decodeName(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.RenameChannel(r.Context(), userID, deviceID, identifier, name); err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Channel renamed successfully", domain.Channel{
+		Identifier: identifier,
+		Name:       name,
+	}, nil))
+}
+
+func (h *Handler) GetLogs(w http.ResponseWriter, r *http.Request) {
+	userID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	deviceID := chi.URLParam(r, "deviceId")
+	if deviceID == "" {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Missing deviceId", nil))
+		return
+	}
+
+	q := r.URL.Query()
+	query := domain.DeviceLogQuery{
+		Cursor: q.Get("cursor"),
+	}
+
+	if types := q.Get("types"); types != "" {
+		query.EventTypes = strings.Split(types, ",")
+	}
+
+	if start := q.Get("start"); start != "" {
+		query.StartTime, err = time.Parse(time.RFC3339, start)
+		if err != nil {
+			api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid start, expected RFC3339", nil))
 			return
 		}
-		api.Respond(w, http.StatusBadGateway, api.NewErrorResponse("UPSTREAM_ERROR", err.Error(), nil))
+	}
+
+	if end := q.Get("end"); end != "" {
+		query.EndTime, err = time.Parse(time.RFC3339, end)
+		if err != nil {
+			api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid end, expected RFC3339", nil))
+			return
+		}
+	}
+
+	if limit := q.Get("limit"); limit != "" {
+		query.Size, err = strconv.Atoi(limit)
+		if err != nil || query.Size <= 0 {
+			api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid limit", nil))
+			return
+		}
+	}
+
+	page, err := h.svc.GetLogs(r.Context(), userID, deviceID, query)
+	if err != nil {
+		respondError(w, err)
 		return
 	}
 
-	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Commands sent successfully", result, nil))
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Device logs retrieved successfully", page.Logs, map[string]any{
+		"next_cursor": page.NextCursor,
+		"has_next":    page.HasNext,
+	}))
+}
+
+func decodeName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req struct {
+		Name string `json:"name"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid request body", nil))
+		return "", false
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Name cannot be empty", nil))
+		return "", false
+	}
+
+	if len([]rune(name)) > maxNameLength {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", fmt.Sprintf("Name cannot exceed %d characters", maxNameLength), nil))
+		return "", false
+	}
+
+	return name, true
+}
+
+func respondError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrAccountNotLinked):
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "No Tuya App Account is linked to the user", nil))
+	case errors.Is(err, domain.ErrDeviceNotOwned):
+		api.Respond(w, http.StatusForbidden, api.NewErrorResponse("FORBIDDEN", "Device does not belong to user", nil))
+	case errors.Is(err, domain.ErrInsufficientRole):
+		api.Respond(w, http.StatusForbidden, api.NewErrorResponse("FORBIDDEN", "User role does not allow this action on the device", nil))
+	case errors.Is(err, domain.ErrChannelNotFound):
+		api.Respond(w, http.StatusNotFound, api.NewErrorResponse("NOT_FOUND", "Channel not found on device", nil))
+	case errors.Is(err, domain.ErrInvalidQuery), errors.Is(err, domain.ErrInvalidCapability):
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", err.Error(), nil))
+	case errors.Is(err, domain.ErrUnsupportedCapability):
+		api.Respond(w, http.StatusUnprocessableEntity, api.NewErrorResponse("UNSUPPORTED_CAPABILITY", err.Error(), nil))
+	default:
+		api.Respond(w, http.StatusBadGateway, api.NewErrorResponse("UPSTREAM_ERROR", err.Error(), nil))
+	}
 }
@@ -2,88 +2,691 @@ package device
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/pkg/api"
 )
 
-type TuyaUIDGetter func(ctx context.Context, userID string) (string, error)
+// TuyaAccountLister returns the user's linked Tuya accounts, or
+// domain.ErrAccountNotLinked when there are none.
+type TuyaAccountLister func(ctx context.Context, userID string) ([]domain.TuyaAccount, error)
+
+type StatusRecorder func(ctx context.Context, devices []domain.Device) error
+
+type CommandAuditor func(ctx context.Context, entry domain.CommandAudit)
+
+type AccessGrantLister func(ctx context.Context, userID string) ([]domain.AccessGrant, error)
 
 type TuyaIoTClient interface {
-	SendCommands(deviceID string, commands any) (json.RawMessage, error)
-	GetMultiChannelName(deviceID string) (json.RawMessage, error)
-	List(tuyaUID string) ([]domain.Device, error)
+	SendCommands(ctx context.Context, deviceID string, commands any) (json.RawMessage, string, error)
+	GetMultiChannelName(ctx context.Context, deviceID string) (json.RawMessage, error)
+	GetStatus(ctx context.Context, deviceID string) ([]domain.DataPoint, error)
+	Get(ctx context.Context, deviceID string) (domain.Device, error)
+	List(ctx context.Context, tuyaUID string) ([]domain.Device, error)
+	ListPage(ctx context.Context, tuyaUID string, pageNo int) ([]domain.Device, bool, error)
+	ListHomeIDs(ctx context.Context, tuyaUID string) ([]string, error)
+	ListRooms(ctx context.Context, homeID string) ([]domain.Room, error)
+	GetLogs(ctx context.Context, deviceID string, query domain.DeviceLogQuery) (domain.DeviceLogPage, error)
+	GetSpecification(ctx context.Context, deviceID string) (domain.DeviceSpec, error)
+	Rename(ctx context.Context, deviceID, name string) error
+	RenameChannel(ctx context.Context, deviceID, identifier, name string) error
+}
+
+type CommandQueue interface {
+	Enqueue(ctx context.Context, cmd domain.Command) (domain.Command, error)
 }
 
+const (
+	maxBatchSize          = 100
+	maxConcurrentCommands = 10
+)
+
+// The confirmation timeout covers the whole call, access check and send
+// included, and is capped well below the server's 10s default write timeout
+// so the per-data-point result always reaches the client.
+const (
+	confirmPollInterval   = 500 * time.Millisecond
+	defaultConfirmTimeout = 3 * time.Second
+	maxConfirmTimeout     = 5 * time.Second
+)
+
+const (
+	defaultDeviceLimit = 100
+	maxDeviceLimit     = 500
+	// deviceSnapshotTTL is how long a user's device list is reused while
+	// they page through it in sorted order.
+	deviceSnapshotTTL = 30 * time.Second
+	// deviceSpecTTL is how long a device specification is cached. Specs only
+	// change with firmware updates.
+	deviceSpecTTL = time.Hour
+)
+
+const (
+	defaultLogWindow = 7 * 24 * time.Hour
+	defaultLogSize   = 20
+	maxLogSize       = 100
+)
+
 type service struct {
-	getTuyaID TuyaUIDGetter
-	tuya      TuyaIoTClient
+	listAccounts TuyaAccountLister
+	tuya         TuyaIoTClient
+	recordStatus StatusRecorder
+	queue        CommandQueue
+	audit        CommandAuditor
+	listGrants   AccessGrantLister
+
+	snapshotsMu sync.Mutex
+	snapshots   map[string]deviceSnapshot
+
+	specsMu sync.Mutex
+	specs   map[string]deviceSpec
 }
 
-func NewService(getTuyaID TuyaUIDGetter, tuya TuyaIoTClient) *service {
-	return &service{getTuyaID: getTuyaID, tuya: tuya}
+// deviceSnapshot is a user's device list as of fetchedAt, before per-page
+// enrichment.
+type deviceSnapshot struct {
+	devices   []domain.Device
+	fetchedAt time.Time
 }
 
-func (s *service) List(ctx context.Context, userID string) ([]domain.Device, error) {
-	tuyaUID, err := s.getTuyaID(ctx, userID)
-	if err != nil {
-		return nil, err
+type accessibleDevice struct {
+	device  domain.Device
+	tuyaUID string
+}
+
+// context routes Tuya calls about the device to its account's region.
+func (a accessibleDevice) context(ctx context.Context) context.Context {
+	return api.NewContextWithRegion(ctx, a.device.Region)
+}
+
+func NewService(listAccounts TuyaAccountLister, tuya TuyaIoTClient, recordStatus StatusRecorder, queue CommandQueue, audit CommandAuditor, listGrants AccessGrantLister) *service {
+	return &service{
+		listAccounts: listAccounts,
+		tuya:         tuya,
+		recordStatus: recordStatus,
+		queue:        queue,
+		audit:        audit,
+		listGrants:   listGrants,
+		snapshots:    make(map[string]deviceSnapshot),
+		specs:        make(map[string]deviceSpec),
 	}
+}
 
-	devices, err := s.tuya.List(tuyaUID)
+// List returns a page of the user's devices, enriching only the devices on
+// the page. Unsorted lists are read lazily, one Tuya page at a time, and the
+// cursor marks where the walk stopped. Sorting needs every device, so sorted
+// pages are cut from a short-lived snapshot of the device list instead, with
+// the ID of the previous page's last device as the cursor.
+func (s *service) List(ctx context.Context, userID string, query domain.DeviceListQuery) (domain.DevicePage, error) {
+	if err := validateListQuery(query); err != nil {
+		return domain.DevicePage{}, err
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultDeviceLimit
+	}
+	if query.Limit > maxDeviceLimit {
+		query.Limit = maxDeviceLimit
+	}
+
+	var page domain.DevicePage
+	var err error
+	if query.Sort == "" {
+		page, err = s.walkDevices(ctx, userID, query)
+	} else {
+		page, err = s.sortedPage(ctx, userID, query)
+	}
 	if err != nil {
-		return nil, err
+		return domain.DevicePage{}, err
 	}
+	page.Fields = query.Fields
+	if len(page.Devices) == 0 {
+		return page, nil
+	}
+
+	s.enrich(ctx, page.Devices, query.Fields)
+	return page, nil
+}
 
-	if len(devices) == 0 {
-		return []domain.Device{}, nil
+// Get returns a single device the user can see, enriched like a list entry.
+func (s *service) Get(ctx context.Context, userID string, deviceID string) (domain.Device, error) {
+	target, err := s.verifyAccess(ctx, userID, deviceID, domain.RoleViewer)
+	if err != nil {
+		return domain.Device{}, err
 	}
 
-	if err := s.enrichDevices(devices); err != nil {
+	devices := []domain.Device{target.device}
+	s.enrich(ctx, devices, nil)
+	return devices[0], nil
+}
+
+// enrich records the devices' status and adds the requested derived fields.
+// Enrichment is best effort: a failure leaves the fields empty.
+func (s *service) enrich(ctx context.Context, devices []domain.Device, fields []string) {
+	if err := s.recordStatus(ctx, devices); err != nil {
 		fmt.Printf("Warning: %v\n", err)
 	}
 
-	return devices, nil
+	if wantsField(fields, "code_name_mapping") {
+		if err := s.enrichDevices(ctx, devices); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+
+	if wantsField(fields, "capabilities") {
+		s.deriveCapabilities(ctx, devices)
+	}
 }
 
-func (s *service) SendCommands(ctx context.Context, userID string, deviceID string, commands []domain.DataPoint) (json.RawMessage, error) {
-	tuyaUID, err := s.getTuyaID(ctx, userID)
+// walkDevices reads the visible accounts' devices in order, a Tuya page at a
+// time, until the page is full.
+func (s *service) walkDevices(ctx context.Context, userID string, query domain.DeviceListQuery) (domain.DevicePage, error) {
+	access, err := s.newAccessResolver(ctx, userID)
+	if err != nil {
+		return domain.DevicePage{}, err
+	}
+	sources := access.sources(ctx)
+
+	pos := walkCursor{Page: 1}
+	if query.Cursor != "" {
+		if pos, err = decodeWalkCursor(query.Cursor); err != nil {
+			return domain.DevicePage{}, err
+		}
+		if pos.Source >= len(sources) {
+			return domain.DevicePage{}, fmt.Errorf("%w: cursor no longer matches an account", domain.ErrInvalidQuery)
+		}
+	}
+
+	match := s.newDeviceMatcher(query.Filter)
+	page := domain.DevicePage{Devices: []domain.Device{}}
+	for pos.Source < len(sources) {
+		src := sources[pos.Source]
+		listed, more, err := s.tuya.ListPage(api.NewContextWithRegion(ctx, src.account.Region), src.account.TuyaUID, pos.Page)
+		if err != nil {
+			if src.grants == nil {
+				return domain.DevicePage{}, fmt.Errorf("failed to list devices of account %q: %w", src.account.Label, err)
+			}
+			fmt.Printf("Warning: failed to list shared devices of owner %s: %v\n", src.account.OwnerID, err)
+			listed, more = nil, false
+		}
+
+		for i := pos.Offset; i < len(listed); i++ {
+			if len(page.Devices) == query.Limit {
+				page.NextCursor = encodeWalkCursor(walkCursor{Source: pos.Source, Page: pos.Page, Offset: i})
+				return page, nil
+			}
+
+			d, ok := access.include(listed[i], src)
+			if !ok {
+				continue
+			}
+			if ok, err = match.matches(ctx, d); err != nil {
+				return domain.DevicePage{}, err
+			}
+			if ok {
+				page.Devices = append(page.Devices, d)
+			}
+		}
+
+		if more {
+			pos = walkCursor{Source: pos.Source, Page: pos.Page + 1}
+		} else {
+			pos = walkCursor{Source: pos.Source + 1, Page: 1}
+		}
+		if len(page.Devices) == query.Limit && pos.Source < len(sources) {
+			page.NextCursor = encodeWalkCursor(pos)
+			return page, nil
+		}
+	}
+
+	return page, nil
+}
+
+// sortedPage cuts a page from the sorted snapshot of the user's devices.
+func (s *service) sortedPage(ctx context.Context, userID string, query domain.DeviceListQuery) (domain.DevicePage, error) {
+	snapshot, err := s.snapshot(ctx, userID, query.Cursor == "")
+	if err != nil {
+		return domain.DevicePage{}, err
+	}
+
+	all, err := s.filterDevices(ctx, snapshot, query.Filter)
+	if err != nil {
+		return domain.DevicePage{}, err
+	}
+	sortDevices(all, query.Sort)
+
+	start := 0
+	if query.Cursor != "" {
+		afterID, err := decodeDeviceCursor(query.Cursor)
+		if err != nil {
+			return domain.DevicePage{}, err
+		}
+		i := slices.IndexFunc(all, func(d domain.Device) bool { return d.ID == afterID })
+		if i < 0 {
+			return domain.DevicePage{}, fmt.Errorf("%w: cursor no longer matches a device", domain.ErrInvalidQuery)
+		}
+		start = i + 1
+	}
+	end := min(start+query.Limit, len(all))
+
+	page := domain.DevicePage{Devices: slices.Clone(all[start:end])}
+	if page.Devices == nil {
+		page.Devices = []domain.Device{}
+	}
+	if end < len(all) {
+		page.NextCursor = encodeDeviceCursor(all[end-1].ID)
+	}
+	return page, nil
+}
+
+// snapshot returns the user's device list, reusing a recent one unless fresh
+// is set. A first page always starts a new snapshot so devices are current.
+func (s *service) snapshot(ctx context.Context, userID string, fresh bool) ([]domain.Device, error) {
+	key := api.GetTenantIDFromContext(ctx) + "/" + userID
+	now := time.Now()
+
+	if !fresh {
+		s.snapshotsMu.Lock()
+		snap, ok := s.snapshots[key]
+		s.snapshotsMu.Unlock()
+		if ok && now.Sub(snap.fetchedAt) < deviceSnapshotTTL {
+			return snap.devices, nil
+		}
+	}
+
+	devices, err := s.allDevices(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	deviceIDs, err := s.getUserDeviceIDs(tuyaUID)
+	s.snapshotsMu.Lock()
+	defer s.snapshotsMu.Unlock()
+	for k, snap := range s.snapshots {
+		if now.Sub(snap.fetchedAt) >= deviceSnapshotTTL {
+			delete(s.snapshots, k)
+		}
+	}
+	s.snapshots[key] = deviceSnapshot{devices: devices, fetchedAt: now}
+
+	return devices, nil
+}
+
+// evictSnapshots drops every snapshot holding the device, the caller's and
+// those of users it is shared with, so the next page reflects a change made
+// through this API.
+func (s *service) evictSnapshots(deviceID string) {
+	s.snapshotsMu.Lock()
+	defer s.snapshotsMu.Unlock()
+	for key, snap := range s.snapshots {
+		if slices.ContainsFunc(snap.devices, func(d domain.Device) bool { return d.ID == deviceID }) {
+			delete(s.snapshots, key)
+		}
+	}
+}
+
+func encodeDeviceCursor(deviceID string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(deviceID))
+}
+
+func decodeDeviceCursor(cursor string) (string, error) {
+	id, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(id) == 0 {
+		return "", fmt.Errorf("%w: malformed cursor", domain.ErrInvalidQuery)
+	}
+	return string(id), nil
+}
+
+// walkCursor is where an unsorted device walk resumes: the index of the
+// account among the visible ones, the Tuya page and the offset within it.
+type walkCursor struct {
+	Source int `json:"s"`
+	Page   int `json:"p"`
+	Offset int `json:"o"`
+}
+
+func encodeWalkCursor(c walkCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeWalkCursor(cursor string) (walkCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
 	if err != nil {
-		return nil, fmt.Errorf("failed to verify device ownership: %w", err)
+		return walkCursor{}, fmt.Errorf("%w: malformed cursor", domain.ErrInvalidQuery)
 	}
 
-	if !contains(deviceIDs, deviceID) {
-		return nil, domain.ErrDeviceNotOwned
+	var c walkCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Source < 0 || c.Page < 1 || c.Offset < 0 {
+		return walkCursor{}, fmt.Errorf("%w: malformed cursor", domain.ErrInvalidQuery)
+	}
+	return c, nil
+}
+
+func (s *service) SendCommands(ctx context.Context, userID string, deviceID string, commands []domain.DataPoint) (json.RawMessage, error) {
+	target, err := s.verifyAccess(ctx, userID, deviceID, domain.RoleOperator)
+	if err != nil {
+		return nil, err
 	}
 
-	result, err := s.tuya.SendCommands(deviceID, commands)
+	result, err := s.sendCommands(target.context(ctx), userID, target.tuyaUID, deviceID, commands, domain.CommandSourceDevice)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send commands: %w", err)
 	}
 	return result, nil
 }
 
-func (s *service) getUserDeviceIDs(tuyaUID string) ([]string, error) {
-	devices, err := s.tuya.List(tuyaUID)
+// SendCommandsAndConfirm sends commands and then polls the device status until
+// every requested value is reported back or timeout, counted from the start of
+// the call, elapses. An unconfirmed
+// result is not an error: Tuya accepted the commands but the device has not
+// reported the new values yet.
+func (s *service) SendCommandsAndConfirm(ctx context.Context, userID string, deviceID string, commands []domain.DataPoint, timeout time.Duration) (domain.CommandConfirmation, error) {
+	if timeout <= 0 {
+		timeout = defaultConfirmTimeout
+	}
+	if timeout > maxConfirmTimeout {
+		timeout = maxConfirmTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	target, err := s.verifyAccess(ctx, userID, deviceID, domain.RoleOperator)
+	if err != nil {
+		return domain.CommandConfirmation{}, err
+	}
+	ctx = target.context(ctx)
+
+	result, err := s.sendCommands(ctx, userID, target.tuyaUID, deviceID, commands, domain.CommandSourceDevice)
+	if err != nil {
+		return domain.CommandConfirmation{}, fmt.Errorf("failed to send commands: %w", err)
+	}
+
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	ticker := time.NewTicker(confirmPollInterval)
+	defer ticker.Stop()
+
+	var status []domain.DataPoint
+	for {
+		select {
+		case <-ctx.Done():
+			return buildConfirmation(result, commands, status), nil
+		case <-ticker.C:
+			latest, err := s.tuya.GetStatus(ctx, deviceID)
+			if err != nil {
+				fmt.Printf("Warning: failed to read status for device %s: %v\n", deviceID, err)
+				continue
+			}
+			status = latest
+
+			confirmation := buildConfirmation(result, commands, status)
+			if confirmation.Confirmed {
+				return confirmation, nil
+			}
+		}
+	}
+}
+
+func (s *service) EnqueueCommands(ctx context.Context, userID string, deviceID string, commands []domain.DataPoint) (domain.Command, error) {
+	target, err := s.verifyAccess(ctx, userID, deviceID, domain.RoleOperator)
+	if err != nil {
+		return domain.Command{}, err
+	}
+
+	cmd, err := s.queue.Enqueue(ctx, domain.Command{
+		TenantID: api.GetTenantIDFromContext(ctx),
+		Region:   target.device.Region,
+		OwnerID:  userID,
+		TuyaUID:  target.tuyaUID,
+		DeviceID: deviceID,
+		Commands: commands,
+	})
+	if err != nil {
+		return domain.Command{}, fmt.Errorf("failed to enqueue commands: %w", err)
+	}
+	return cmd, nil
+}
+
+func (s *service) SendBatchCommands(ctx context.Context, userID string, batch []domain.DeviceCommands) ([]domain.CommandResult, error) {
+	if len(batch) > maxBatchSize {
+		return nil, fmt.Errorf("%w: batch cannot exceed %d entries", domain.ErrInvalidQuery, maxBatchSize)
+	}
+
+	// Ownership is checked once against the full device list rather than
+	// with a lookup per entry, so a large batch costs a few list calls.
+	access, err := s.newAccessResolver(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
-	ids := make([]string, len(devices))
-	for i, d := range devices {
-		ids[i] = d.ID
+	accessible, err := s.accessibleDevices(ctx, access)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]accessibleDevice, len(accessible))
+	for _, a := range accessible {
+		byID[a.device.ID] = a
+	}
+
+	results := make([]domain.CommandResult, len(batch))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentCommands)
+
+	for i, entry := range batch {
+		target, ok := byID[entry.DeviceID]
+		if !ok {
+			results[i] = domain.CommandResult{DeviceID: entry.DeviceID, Status: http.StatusForbidden, Error: domain.ErrDeviceNotOwned.Error()}
+			continue
+		}
+		if !domain.RoleAllows(target.device.Role, domain.RoleOperator) {
+			results[i] = domain.CommandResult{DeviceID: entry.DeviceID, Status: http.StatusForbidden, Error: domain.ErrInsufficientRole.Error()}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, entry domain.DeviceCommands, target accessibleDevice) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result := domain.CommandResult{DeviceID: entry.DeviceID}
+			raw, err := s.sendCommands(target.context(ctx), userID, target.tuyaUID, entry.DeviceID, entry.Commands, domain.CommandSourceBatch)
+			if err != nil {
+				result.Status = http.StatusBadGateway
+				result.Error = err.Error()
+			} else {
+				result.Status = http.StatusOK
+				result.Success = true
+				result.Result = raw
+			}
+			results[i] = result
+		}(i, entry, target)
 	}
-	return ids, nil
+
+	wg.Wait()
+	return results, nil
+}
+
+func (s *service) Rename(ctx context.Context, userID string, deviceID string, name string) error {
+	target, err := s.verifyAccess(ctx, userID, deviceID, domain.RoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	if err := s.tuya.Rename(target.context(ctx), deviceID, name); err != nil {
+		return fmt.Errorf("failed to rename device: %w", err)
+	}
+	s.evictSnapshots(deviceID)
+	return nil
+}
+
+func (s *service) RenameChannel(ctx context.Context, userID string, deviceID string, identifier string, name string) error {
+	target, err := s.verifyAccess(ctx, userID, deviceID, domain.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	ctx = target.context(ctx)
+
+	channels, err := s.getChannels(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, ch := range channels {
+		if ch.Identifier == identifier {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return domain.ErrChannelNotFound
+	}
+
+	if err := s.tuya.RenameChannel(ctx, deviceID, identifier, name); err != nil {
+		return fmt.Errorf("failed to rename channel: %w", err)
+	}
+	s.evictSnapshots(deviceID)
+	return nil
 }
 
-func (s *service) enrichDevices(devices []domain.Device) error {
+func (s *service) GetLogs(ctx context.Context, userID string, deviceID string, query domain.DeviceLogQuery) (domain.DeviceLogPage, error) {
+	if len(query.EventTypes) == 0 {
+		query.EventTypes = domain.DefaultDeviceLogEvents
+	}
+	if query.EndTime.IsZero() {
+		query.EndTime = time.Now()
+	}
+	if query.StartTime.IsZero() {
+		query.StartTime = query.EndTime.Add(-defaultLogWindow)
+	}
+	if !query.StartTime.Before(query.EndTime) {
+		return domain.DeviceLogPage{}, fmt.Errorf("%w: start must be before end", domain.ErrInvalidQuery)
+	}
+	if query.Size <= 0 {
+		query.Size = defaultLogSize
+	}
+	if query.Size > maxLogSize {
+		query.Size = maxLogSize
+	}
+
+	target, err := s.verifyAccess(ctx, userID, deviceID, domain.RoleViewer)
+	if err != nil {
+		return domain.DeviceLogPage{}, err
+	}
+
+	page, err := s.tuya.GetLogs(target.context(ctx), deviceID, query)
+	if err != nil {
+		return domain.DeviceLogPage{}, fmt.Errorf("failed to get device logs: %w", err)
+	}
+	return page, nil
+}
+
+// VerifyAccess checks that userID holds at least role on deviceID and returns
+// the device and the Tuya UID of its account, for features that reach Tuya
+// through a device they do not manage themselves, such as a hub's infrared
+// remotes.
+func (s *service) VerifyAccess(ctx context.Context, userID string, deviceID string, role string) (domain.Device, string, error) {
+	target, err := s.verifyAccess(ctx, userID, deviceID, role)
+	if err != nil {
+		return domain.Device{}, "", err
+	}
+	return target.device, target.tuyaUID, nil
+}
+
+// verifyAccess checks that userID holds at least role on deviceID and returns
+// the device together with the account it belongs to.
+func (s *service) verifyAccess(ctx context.Context, userID string, deviceID string, role string) (accessibleDevice, error) {
+	access, err := s.newAccessResolver(ctx, userID)
+	if err != nil {
+		return accessibleDevice{}, err
+	}
+	return access.verify(ctx, deviceID, role)
+}
+
+// allDevices lists every device visible to the user: those of each account
+// they linked, followed by devices other owners shared with them.
+func (s *service) allDevices(ctx context.Context, userID string) ([]domain.Device, error) {
+	access, err := s.newAccessResolver(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessible, err := s.accessibleDevices(ctx, access)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]domain.Device, len(accessible))
+	for i, a := range accessible {
+		devices[i] = a.device
+	}
+	return devices, nil
+}
+
+// accessibleDevices lists the devices of every source the resolver knows,
+// with the Tuya UID of the account each is reached through.
+func (s *service) accessibleDevices(ctx context.Context, access *accessResolver) ([]accessibleDevice, error) {
+	var devices []accessibleDevice
+	seen := make(map[string]int)
+	for _, src := range access.sources(ctx) {
+		listed, err := s.tuya.List(api.NewContextWithRegion(ctx, src.account.Region), src.account.TuyaUID)
+		if err != nil {
+			if src.grants == nil {
+				return nil, fmt.Errorf("failed to list devices of account %q: %w", src.account.Label, err)
+			}
+			fmt.Printf("Warning: failed to list shared devices of owner %s: %v\n", src.account.OwnerID, err)
+			continue
+		}
+
+		for _, d := range listed {
+			d, ok := access.include(d, src)
+			if !ok {
+				continue
+			}
+			if i, ok := seen[d.ID]; ok {
+				devices[i].device.Role = domain.HigherRole(devices[i].device.Role, d.Role)
+				continue
+			}
+			seen[d.ID] = len(devices)
+			devices = append(devices, accessibleDevice{device: d, tuyaUID: src.account.TuyaUID})
+		}
+	}
+
+	return devices, nil
+}
+
+func (s *service) sendCommands(ctx context.Context, userID, tuyaUID, deviceID string, commands []domain.DataPoint, source string) (json.RawMessage, error) {
+	start := time.Now()
+	result, tid, err := s.tuya.SendCommands(ctx, deviceID, commands)
+
+	entry := domain.CommandAudit{
+		OwnerID:   userID,
+		TuyaUID:   tuyaUID,
+		DeviceID:  deviceID,
+		Commands:  commands,
+		Source:    source,
+		Success:   err == nil,
+		Result:    result,
+		Tid:       tid,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	s.audit(ctx, entry)
+
+	return result, err
+}
+
+func (s *service) enrichDevices(ctx context.Context, devices []domain.Device) error {
 	var devicesToEnrich []*domain.Device
 	for i := range devices {
 		device := &devices[i]
@@ -96,12 +699,12 @@ func (s *service) enrichDevices(devices []domain.Device) error {
 	}
 
 	if len(devicesToEnrich) > 0 {
-		return s.enrichWithChannelNames(devicesToEnrich)
+		return s.enrichWithChannelNames(ctx, devicesToEnrich)
 	}
 	return nil
 }
 
-func (s *service) enrichWithChannelNames(devices []*domain.Device) error {
+func (s *service) enrichWithChannelNames(ctx context.Context, devices []*domain.Device) error {
 	var wg sync.WaitGroup
 	errs := make(chan error, len(devices))
 
@@ -110,19 +713,11 @@ func (s *service) enrichWithChannelNames(devices []*domain.Device) error {
 		go func(device *domain.Device) {
 			defer wg.Done()
 
-			result, err := s.tuya.GetMultiChannelName(device.ID)
+			channels, err := s.getChannels(api.NewContextWithRegion(ctx, device.Region), device.ID)
 			if err != nil {
-				errs <- fmt.Errorf("failed to get channel name for device %s: %w", device.ID, err)
+				errs <- err
 				return
 			}
-
-			var channels []domain.Channel
-			if len(result) > 0 {
-				if err := json.Unmarshal(result, &channels); err != nil {
-					errs <- fmt.Errorf("failed to decode channels for device %s: %w", device.ID, err)
-					return
-				}
-			}
 			device.CodeNameMapping = channels
 		}(device)
 	}
@@ -144,11 +739,44 @@ func (s *service) enrichWithChannelNames(devices []*domain.Device) error {
 	return nil
 }
 
-func contains(slice []string, item string) bool {
-	for _, v := range slice {
-		if v == item {
-			return true
+func (s *service) getChannels(ctx context.Context, deviceID string) ([]domain.Channel, error) {
+	result, err := s.tuya.GetMultiChannelName(ctx, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel name for device %s: %w", deviceID, err)
+	}
+
+	var channels []domain.Channel
+	if len(result) > 0 {
+		if err := json.Unmarshal(result, &channels); err != nil {
+			return nil, fmt.Errorf("failed to decode channels for device %s: %w", deviceID, err)
+		}
+	}
+	return channels, nil
+}
+
+func buildConfirmation(result json.RawMessage, requested, reported []domain.DataPoint) domain.CommandConfirmation {
+	matches := domain.MatchDataPoints(requested, reported)
+
+	current := make(map[string]any, len(reported))
+	for _, dp := range reported {
+		current[dp.Code] = dp.Value
+	}
+
+	confirmation := domain.CommandConfirmation{
+		Result:     result,
+		Confirmed:  true,
+		DataPoints: make([]domain.DataPointConfirmation, len(requested)),
+	}
+	for i, dp := range requested {
+		confirmation.DataPoints[i] = domain.DataPointConfirmation{
+			Code:      dp.Code,
+			Requested: dp.Value,
+			Reported:  current[dp.Code],
+			Confirmed: matches[dp.Code],
+		}
+		if !matches[dp.Code] {
+			confirmation.Confirmed = false
 		}
 	}
-	return false
+	return confirmation
 }
@@ -1,15 +1,40 @@
 package device
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/internal/tuya"
+	"github.com/avagenc/zee-api/pkg/api"
 )
 
+var tuyaLogEventTypes = map[string]int{
+	domain.DeviceLogEventOnline:   1,
+	domain.DeviceLogEventOffline:  2,
+	domain.DeviceLogEventActivate: 3,
+	domain.DeviceLogEventReset:    4,
+	domain.DeviceLogEventCommand:  5,
+	domain.DeviceLogEventUpgrade:  6,
+	domain.DeviceLogEventReport:   7,
+}
+
+const tuyaDevicePageSize = 50
+
+// Tuya reports a device that does not exist or lies outside the cloud project
+// as a permission error.
+const tuyaPermissionDenied = 1106
+
 type TuyaClient interface {
-	Do(method, path string, body []byte) (json.RawMessage, error)
+	Do(ctx context.Context, method, path string, body []byte) (json.RawMessage, error)
+	DoWithTID(ctx context.Context, method, path string, body []byte) (json.RawMessage, string, error)
+	Send(ctx context.Context, req *tuya.Request) (json.RawMessage, string, error)
 }
 
 type tuyaIoTClient struct {
@@ -20,22 +45,155 @@ func NewTuyaIoTClient(client TuyaClient) TuyaIoTClient {
 	return &tuyaIoTClient{client: client}
 }
 
-func (c *tuyaIoTClient) List(tuyaUID string) ([]domain.Device, error) {
-	path := fmt.Sprintf("%s/%s/devices", domain.TuyaUserEndpoint, tuyaUID)
-	result, err := c.client.Do(http.MethodGet, path, nil)
+// cloudDevice is a device as /v1.0/devices reports it, where owner_id is the
+// home the device belongs to and uid the Tuya user that owns it.
+type cloudDevice struct {
+	domain.Device
+	OwnerID string `json:"owner_id"`
+	UID     string `json:"uid"`
+}
+
+func (d cloudDevice) device() domain.Device {
+	device := d.Device
+	device.HomeID = d.OwnerID
+	device.UID = d.UID
+	return device
+}
+
+// List walks the user's devices page by page, so large inventories are
+// fetched in bounded requests rather than a single response.
+func (c *tuyaIoTClient) List(ctx context.Context, tuyaUID string) ([]domain.Device, error) {
+	var devices []domain.Device
+	for pageNo := 1; ; pageNo++ {
+		page, more, err := c.ListPage(ctx, tuyaUID, pageNo)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, page...)
+
+		if !more {
+			return devices, nil
+		}
+	}
+}
+
+// ListPage returns one page of the user's devices, numbered from 1, and
+// whether another page follows.
+func (c *tuyaIoTClient) ListPage(ctx context.Context, tuyaUID string, pageNo int) ([]domain.Device, bool, error) {
+	req := tuya.NewRequest(http.MethodGet, domain.TuyaCloudDevicesEndpoint).
+		WithQuery("source_type", "tuyaUser").
+		WithQuery("source_id", tuyaUID).
+		WithQuery("page_no", strconv.Itoa(pageNo)).
+		WithQuery("page_size", strconv.Itoa(tuyaDevicePageSize))
+
+	result, _, err := c.client.Send(ctx, req)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var page struct {
+		Devices []cloudDevice `json:"devices"`
+		Total   int           `json:"total"`
+	}
+	if err := json.Unmarshal(result, &page); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal device list: %w", err)
+	}
+
+	devices := make([]domain.Device, len(page.Devices))
+	for i, d := range page.Devices {
+		devices[i] = d.device()
+	}
+
+	more := len(page.Devices) == tuyaDevicePageSize && pageNo*tuyaDevicePageSize < page.Total
+	return devices, more, nil
+}
+
+// Get looks up a single device, including the Tuya user that owns it. A
+// device Tuya does not expose to the project is reported as
+// domain.ErrDeviceNotOwned.
+func (c *tuyaIoTClient) Get(ctx context.Context, deviceID string) (domain.Device, error) {
+	req := tuya.NewRequest(http.MethodGet, fmt.Sprintf("%s/%s", domain.TuyaCloudDevicesEndpoint, deviceID))
+	result, _, err := c.client.Send(ctx, req)
+	if err != nil {
+		var tuyaErr *tuya.Error
+		if errors.As(err, &tuyaErr) && tuyaErr.Code == tuyaPermissionDenied {
+			return domain.Device{}, fmt.Errorf("%w: %v", domain.ErrDeviceNotOwned, err)
+		}
+		return domain.Device{}, err
+	}
+
+	var device cloudDevice
+	if err := json.Unmarshal(result, &device); err != nil {
+		return domain.Device{}, fmt.Errorf("failed to unmarshal device: %w", err)
+	}
+	return device.device(), nil
+}
+
+// ListHomeIDs returns the homes the Tuya user owns or is a member of.
+func (c *tuyaIoTClient) ListHomeIDs(ctx context.Context, tuyaUID string) ([]string, error) {
+	path := fmt.Sprintf("%s/%s/homes", domain.TuyaUserEndpoint, tuyaUID)
+	result, err := c.client.Do(ctx, http.MethodGet, path, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	var devices []domain.Device
-	if err := json.Unmarshal(result, &devices); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal device list: %w", err)
+	var homes []struct {
+		HomeID json.Number `json:"home_id"`
+	}
+	if err := json.Unmarshal(result, &homes); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal homes: %w", err)
 	}
 
-	return devices, nil
+	ids := make([]string, len(homes))
+	for i, h := range homes {
+		ids[i] = h.HomeID.String()
+	}
+	return ids, nil
 }
 
-func (c *tuyaIoTClient) SendCommands(deviceID string, commands any) (json.RawMessage, error) {
+// ListRooms returns the rooms of a home with the IDs of the devices in each.
+func (c *tuyaIoTClient) ListRooms(ctx context.Context, homeID string) ([]domain.Room, error) {
+	path := fmt.Sprintf("%s/%s/rooms", domain.TuyaHomesEndpoint, homeID)
+	result, err := c.client.Do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var home struct {
+		Rooms []struct {
+			RoomID json.Number `json:"room_id"`
+			Name   string      `json:"name"`
+		} `json:"rooms"`
+	}
+	if err := json.Unmarshal(result, &home); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rooms: %w", err)
+	}
+
+	rooms := make([]domain.Room, len(home.Rooms))
+	for i, r := range home.Rooms {
+		path := fmt.Sprintf("%s/%s/rooms/%s/devices", domain.TuyaHomesEndpoint, homeID, r.RoomID)
+		result, err := c.client.Do(ctx, http.MethodGet, path, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var devices []struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(result, &devices); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal devices of room %s: %w", r.RoomID, err)
+		}
+
+		rooms[i] = domain.Room{ID: r.RoomID.String(), Name: r.Name, DeviceIDs: make([]string, len(devices))}
+		for j, d := range devices {
+			rooms[i].DeviceIDs[j] = d.ID
+		}
+	}
+
+	return rooms, nil
+}
+
+func (c *tuyaIoTClient) SendCommands(ctx context.Context, deviceID string, commands any) (json.RawMessage, string, error) {
 	path := fmt.Sprintf("%s/%s/commands", domain.TuyaDevicesEndpoint, deviceID)
 	bodyBytes, err := json.Marshal(struct {
 		Commands any `json:"commands"`
@@ -43,13 +201,146 @@ func (c *tuyaIoTClient) SendCommands(deviceID string, commands any) (json.RawMes
 		Commands: commands,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal command payload: %w", err)
+		return nil, "", fmt.Errorf("%w: failed to marshal command payload: %v", tuya.ErrNotSent, err)
+	}
+
+	api.MarkUpstreamWrite(ctx)
+	return c.client.DoWithTID(ctx, http.MethodPost, path, bodyBytes)
+}
+
+func (c *tuyaIoTClient) GetStatus(ctx context.Context, deviceID string) ([]domain.DataPoint, error) {
+	path := fmt.Sprintf("%s/%s/status", domain.TuyaDevicesEndpoint, deviceID)
+	result, err := c.client.Do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var status []domain.DataPoint
+	if err := json.Unmarshal(result, &status); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal device status: %w", err)
+	}
+
+	return status, nil
+}
+
+func (c *tuyaIoTClient) GetSpecification(ctx context.Context, deviceID string) (domain.DeviceSpec, error) {
+	path := fmt.Sprintf("%s/%s/specification", domain.TuyaDevicesEndpoint, deviceID)
+	result, err := c.client.Do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return domain.DeviceSpec{}, err
 	}
 
-	return c.client.Do(http.MethodPost, path, bodyBytes)
+	var spec domain.DeviceSpec
+	if err := json.Unmarshal(result, &spec); err != nil {
+		return domain.DeviceSpec{}, fmt.Errorf("failed to unmarshal device specification: %w", err)
+	}
+
+	return spec, nil
 }
 
-func (c *tuyaIoTClient) GetMultiChannelName(deviceID string) (json.RawMessage, error) {
+func (c *tuyaIoTClient) GetMultiChannelName(ctx context.Context, deviceID string) (json.RawMessage, error) {
 	path := fmt.Sprintf("%s/%s/multiple-names", domain.TuyaDevicesEndpoint, deviceID)
-	return c.client.Do(http.MethodGet, path, nil)
+	return c.client.Do(ctx, http.MethodGet, path, nil)
+}
+
+func (c *tuyaIoTClient) Rename(ctx context.Context, deviceID, name string) error {
+	path := fmt.Sprintf("%s/%s", domain.TuyaDevicesEndpoint, deviceID)
+	bodyBytes, err := json.Marshal(struct {
+		Name string `json:"name"`
+	}{
+		Name: name,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal rename payload: %w", err)
+	}
+
+	api.MarkUpstreamWrite(ctx)
+	_, err = c.client.Do(ctx, http.MethodPut, path, bodyBytes)
+	return err
+}
+
+func (c *tuyaIoTClient) RenameChannel(ctx context.Context, deviceID, identifier, name string) error {
+	path := fmt.Sprintf("%s/%s/multiple-name", domain.TuyaDevicesEndpoint, deviceID)
+	bodyBytes, err := json.Marshal(domain.Channel{
+		Identifier: identifier,
+		Name:       name,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal channel rename payload: %w", err)
+	}
+
+	api.MarkUpstreamWrite(ctx)
+	_, err = c.client.Do(ctx, http.MethodPut, path, bodyBytes)
+	return err
+}
+
+func (c *tuyaIoTClient) GetLogs(ctx context.Context, deviceID string, query domain.DeviceLogQuery) (domain.DeviceLogPage, error) {
+	types := make([]string, 0, len(query.EventTypes))
+	for _, eventType := range query.EventTypes {
+		code, ok := tuyaLogEventTypes[eventType]
+		if !ok {
+			return domain.DeviceLogPage{}, fmt.Errorf("%w: unknown event type %q", domain.ErrInvalidQuery, eventType)
+		}
+		types = append(types, strconv.Itoa(code))
+	}
+
+	req := tuya.NewRequest(http.MethodGet, fmt.Sprintf("%s/%s/logs", domain.TuyaDevicesEndpoint, deviceID)).
+		WithQuery("type", strings.Join(types, ",")).
+		WithQuery("start_time", strconv.FormatInt(query.StartTime.UnixMilli(), 10)).
+		WithQuery("end_time", strconv.FormatInt(query.EndTime.UnixMilli(), 10)).
+		WithQuery("size", strconv.Itoa(query.Size))
+	if query.Cursor != "" {
+		req.WithQuery("start_row_key", query.Cursor)
+	}
+
+	result, _, err := c.client.Send(ctx, req)
+	if err != nil {
+		return domain.DeviceLogPage{}, err
+	}
+
+	var page struct {
+		Logs []struct {
+			EventID   int    `json:"event_id"`
+			EventTime int64  `json:"event_time"`
+			EventFrom string `json:"event_from"`
+			Code      string `json:"code"`
+			Value     any    `json:"value"`
+		} `json:"logs"`
+		HasNext    bool   `json:"has_next"`
+		NextRowKey string `json:"next_row_key"`
+	}
+	if err := json.Unmarshal(result, &page); err != nil {
+		return domain.DeviceLogPage{}, fmt.Errorf("failed to unmarshal device logs: %w", err)
+	}
+
+	logs := make([]domain.DeviceLog, len(page.Logs))
+	for i, l := range page.Logs {
+		logs[i] = domain.DeviceLog{
+			EventType: tuyaLogEventName(l.EventID),
+			EventTime: time.UnixMilli(l.EventTime).UTC(),
+			EventFrom: l.EventFrom,
+			Code:      l.Code,
+			Value:     l.Value,
+		}
+	}
+
+	nextCursor := ""
+	if page.HasNext {
+		nextCursor = page.NextRowKey
+	}
+
+	return domain.DeviceLogPage{
+		Logs:       logs,
+		NextCursor: nextCursor,
+		HasNext:    page.HasNext,
+	}, nil
+}
+
+func tuyaLogEventName(eventID int) string {
+	for name, code := range tuyaLogEventTypes {
+		if code == eventID {
+			return name
+		}
+	}
+	return strconv.Itoa(eventID)
 }
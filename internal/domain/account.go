@@ -1,5 +1,36 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
-var ErrAccountNotLinked = errors.New("no tuya account linked to user")
+var (
+	ErrAccountNotLinked    = errors.New("no tuya account linked to user")
+	ErrTuyaAccountNotFound = errors.New("tuya account not found")
+	ErrTuyaAccountConflict = errors.New("tuya account or label already linked")
+)
+
+const DefaultTuyaAccountLabel = "Primary"
+
+// TuyaAccount is one Tuya app account linked to an owner. An owner may link
+// several; exactly one is the default used when no account is specified.
+type TuyaAccount struct {
+	ID        string    `json:"id"`
+	OwnerID   string    `json:"owner_id"`
+	TenantID  string    `json:"tenant_id,omitempty"`
+	TuyaUID   string    `json:"tuya_uid"`
+	Region    string    `json:"region,omitempty"`
+	Label     string    `json:"label"`
+	IsDefault bool      `json:"is_default"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TuyaAccountUpdate holds the changes to a linked account; zero values leave
+// the field unchanged.
+type TuyaAccountUpdate struct {
+	Label       string
+	Region      string
+	MakeDefault bool
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrInvalidAPIKey  = errors.New("invalid api key")
+	ErrInvalidScope   = errors.New("invalid scope")
+)
+
+// ScopeCommandsSend covers every operation that changes device state or
+// configuration, not only command submission. ScopeImpersonate lets trusted
+// internal callers name the user in the x-user-id header; admin does not
+// imply it.
+const (
+	ScopeDevicesRead  = "devices:read"
+	ScopeCommandsSend = "commands:send"
+	ScopeImpersonate  = "users:impersonate"
+	ScopeAdmin        = "admin"
+)
+
+var Scopes = []string{ScopeDevicesRead, ScopeCommandsSend, ScopeImpersonate, ScopeAdmin}
+
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	TenantID   string     `json:"tenant_id,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Hash       string     `json:"-"`
+}
+
+func (k APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
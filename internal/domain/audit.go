@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	CommandSourceDevice     = "device"
+	CommandSourceBatch      = "batch"
+	CommandSourceGroup      = "group"
+	CommandSourceAsync      = "async"
+	CommandSourceCapability = "capability"
+	CommandSourceInfrared   = "infrared"
+)
+
+type CommandAudit struct {
+	ID        int64           `json:"id"`
+	TenantID  string          `json:"tenant_id,omitempty"`
+	OwnerID   string          `json:"owner_id"`
+	TuyaUID   string          `json:"tuya_uid"`
+	DeviceID  string          `json:"device_id"`
+	RemoteID  string          `json:"remote_id,omitempty"`
+	Commands  []DataPoint     `json:"commands"`
+	Source    string          `json:"source"`
+	RequestID string          `json:"request_id,omitempty"`
+	Success   bool            `json:"success"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	Tid       string          `json:"tid,omitempty"`
+	LatencyMs int64           `json:"latency_ms"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type CommandAuditFilter struct {
+	TenantID  string
+	OwnerID   string
+	TuyaUID   string
+	DeviceID  string
+	RequestID string
+	Tid       string
+	Success   *bool
+	From      time.Time
+	To        time.Time
+	BeforeID  int64
+	Limit     int
+}
+
+type CommandAuditPage struct {
+	Entries    []CommandAudit
+	NextCursor string
+}
@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+var (
+	ErrUnsupportedCapability = errors.New("capability not supported by device")
+	ErrInvalidCapability     = errors.New("invalid capability command")
+)
+
+const (
+	CapabilityOnOff            = "on_off"
+	CapabilityBrightness       = "brightness"
+	CapabilityColorTemperature = "color_temperature"
+	CapabilityColor            = "color"
+	CapabilityThermostat       = "thermostat"
+	CapabilityCover            = "cover"
+	CapabilityPowerMetering    = "power_metering"
+)
+
+// Capability is a device feature in Tuya-independent terms. Instance names
+// the data point behind it when a device has several of the same type, such as
+// the gangs of a multi-switch. State holds one of the *State types below.
+type Capability struct {
+	Type     string `json:"type"`
+	Instance string `json:"instance,omitempty"`
+	State    any    `json:"state"`
+}
+
+type OnOffState struct {
+	On bool `json:"on"`
+}
+
+// LevelState is a percentage from 0 to 100, used for brightness.
+type LevelState struct {
+	Percent float64 `json:"percent"`
+}
+
+// ColorTemperatureState percent runs from warmest at 0 to coolest at 100.
+// Kelvin is approximate, as Tuya does not report the light's actual range.
+type ColorTemperatureState struct {
+	Percent float64 `json:"percent"`
+	Kelvin  int     `json:"kelvin"`
+}
+
+// ColorState has hue in degrees from 0 to 360 and saturation and value as
+// percentages.
+type ColorState struct {
+	Hue        float64 `json:"hue"`
+	Saturation float64 `json:"saturation"`
+	Value      float64 `json:"value"`
+}
+
+type RGB struct {
+	R int `json:"r"`
+	G int `json:"g"`
+	B int `json:"b"`
+}
+
+// LightColor is a color given in exactly one of its forms. Hex accepts
+// "#rrggbb", "rrggbb" and the short "#rgb".
+type LightColor struct {
+	Hex string      `json:"hex,omitempty"`
+	RGB *RGB        `json:"rgb,omitempty"`
+	HSV *ColorState `json:"hsv,omitempty"`
+}
+
+// LightCommand sets a light. Color switches it to color mode and Kelvin to
+// white mode, so the two cannot be combined. Brightness is a percentage that
+// applies to whichever mode the light ends up in.
+type LightCommand struct {
+	On         *bool       `json:"on,omitempty"`
+	Brightness *float64    `json:"brightness,omitempty"`
+	Color      *LightColor `json:"color,omitempty"`
+	Kelvin     *float64    `json:"kelvin,omitempty"`
+}
+
+// ThermostatState temperatures are in degrees Celsius.
+type ThermostatState struct {
+	Target  *float64 `json:"target,omitempty"`
+	Current *float64 `json:"current,omitempty"`
+	Mode    string   `json:"mode,omitempty"`
+}
+
+const (
+	CoverActionOpen  = "open"
+	CoverActionClose = "close"
+	CoverActionStop  = "stop"
+)
+
+// CoverState position is a percentage where 100 is fully open.
+type CoverState struct {
+	Position *float64 `json:"position,omitempty"`
+	Action   string   `json:"action,omitempty"`
+}
+
+type PowerMeteringState struct {
+	Watts *float64 `json:"watts,omitempty"`
+	Volts *float64 `json:"volts,omitempty"`
+	Amps  *float64 `json:"amps,omitempty"`
+}
+
+// CapabilityCommand sets a capability. State is the JSON of the capability's
+// state type; fields left out are not changed.
+type CapabilityCommand struct {
+	Type     string          `json:"type"`
+	Instance string          `json:"instance,omitempty"`
+	State    json.RawMessage `json:"state"`
+}
+
+// DataPointSpec describes a data point in a device specification. Values is
+// the JSON string Tuya uses for ranges, scales and enum members.
+type DataPointSpec struct {
+	Code   string `json:"code"`
+	Type   string `json:"type"`
+	Values string `json:"values"`
+}
+
+type DeviceSpec struct {
+	Category  string          `json:"category"`
+	Functions []DataPointSpec `json:"functions"`
+	Status    []DataPointSpec `json:"status"`
+}
@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+var ErrCommandNotFound = errors.New("command not found")
+
+// A command moves from queued to sending while a worker holds it, then to
+// sent once Tuya accepted it. Confirmed, unconfirmed and failed are final;
+// unconfirmed means the command may have reached the device but it never
+// reported the requested values.
+const (
+	CommandStateQueued      = "queued"
+	CommandStateSending     = "sending"
+	CommandStateSent        = "sent"
+	CommandStateConfirmed   = "confirmed"
+	CommandStateUnconfirmed = "unconfirmed"
+	CommandStateFailed      = "failed"
+)
+
+type DeviceCommands struct {
+	DeviceID string      `json:"deviceId"`
+	Commands []DataPoint `json:"commands"`
+}
+
+type CommandResult struct {
+	DeviceID string          `json:"device_id"`
+	Status   int             `json:"status"`
+	Success  bool            `json:"success"`
+	Result   json.RawMessage `json:"result,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+type Command struct {
+	ID          string          `json:"id"`
+	OwnerID     string          `json:"-"`
+	TuyaUID     string          `json:"-"`
+	TenantID    string          `json:"-"`
+	Region      string          `json:"-"`
+	DeviceID    string          `json:"device_id"`
+	Commands    []DataPoint     `json:"commands"`
+	State       string          `json:"state"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	Attempts    int             `json:"attempts"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	SentAt      *time.Time      `json:"sent_at,omitempty"`
+	ConfirmedAt *time.Time      `json:"confirmed_at,omitempty"`
+}
+
+type DataPointConfirmation struct {
+	Code      string `json:"code"`
+	Requested any    `json:"requested"`
+	Reported  any    `json:"reported,omitempty"`
+	Confirmed bool   `json:"confirmed"`
+}
+
+type CommandConfirmation struct {
+	Result     json.RawMessage         `json:"result,omitempty"`
+	Confirmed  bool                    `json:"confirmed"`
+	DataPoints []DataPointConfirmation `json:"data_points"`
+}
+
+// MatchDataPoints reports, for each requested code, whether the reported
+// status carries the same value. Values are compared after normalising both
+// sides through JSON so that 1 and 1.0 compare equal.
+func MatchDataPoints(requested, reported []DataPoint) map[string]bool {
+	current := make(map[string]any, len(reported))
+	for _, dp := range reported {
+		current[dp.Code] = normalizeValue(dp.Value)
+	}
+
+	matches := make(map[string]bool, len(requested))
+	for _, dp := range requested {
+		value, ok := current[dp.Code]
+		matches[dp.Code] = ok && reflect.DeepEqual(value, normalizeValue(dp.Value))
+	}
+	return matches
+}
+
+func normalizeValue(v any) any {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	var out any
+	if err := json.Unmarshal(b, &out); err != nil {
+		return fmt.Sprint(v)
+	}
+	return out
+}
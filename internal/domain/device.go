@@ -2,7 +2,10 @@ package domain
 
 import "errors"
 
-var ErrDeviceNotOwned = errors.New("device does not belong to user")
+var (
+	ErrDeviceNotOwned  = errors.New("device does not belong to user")
+	ErrChannelNotFound = errors.New("channel not found on device")
+)
 
 type DataPoint struct {
 	Code  string `json:"code"`
@@ -15,9 +18,61 @@ type Channel struct {
 }
 
 type Device struct {
-	ID              string      `json:"id"`
-	Category        string      `json:"category"`
-	Name            string      `json:"name"`
-	Status          []DataPoint `json:"status"`
-	CodeNameMapping []Channel   `json:"code_name_mapping"`
+	ID              string       `json:"id"`
+	Category        string       `json:"category"`
+	Name            string       `json:"name"`
+	Online          bool         `json:"online"`
+	HomeID          string       `json:"home_id,omitempty"`
+	Role            string       `json:"role,omitempty"`
+	AccountID       string       `json:"account_id,omitempty"`
+	AccountLabel    string       `json:"account_label,omitempty"`
+	Region          string       `json:"region,omitempty"`
+	Status          []DataPoint  `json:"status"`
+	CodeNameMapping []Channel    `json:"code_name_mapping"`
+	Capabilities    []Capability `json:"capabilities,omitempty"`
+	// UID is the Tuya user that owns the device. It is used to check access
+	// and is not exposed.
+	UID string `json:"-"`
+}
+
+// DeviceFields are the fields a device list can be trimmed to.
+var DeviceFields = []string{
+	"id", "category", "name", "online", "home_id", "role", "account_id", "account_label", "region", "status", "code_name_mapping", "capabilities",
+}
+
+const (
+	DeviceSortName     = "name"
+	DeviceSortCategory = "category"
+)
+
+// DeviceFilter narrows a device list. Zero values match every device; Status
+// matches devices reporting each data point with the given value.
+type DeviceFilter struct {
+	Category string
+	Online   *bool
+	Name     string
+	Room     string
+	Status   []DataPoint
+}
+
+type DeviceListQuery struct {
+	Limit  int
+	Cursor string
+	Filter DeviceFilter
+	// Sort is a DeviceSort value, prefixed with "-" for descending order.
+	Sort   string
+	Fields []string
+}
+
+type Room struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	DeviceIDs []string `json:"device_ids"`
+}
+
+type DevicePage struct {
+	Devices    []Device
+	NextCursor string
+	// Fields is the sparse fieldset the page was requested with, if any.
+	Fields []string
 }
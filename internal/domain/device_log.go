@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrInvalidQuery = errors.New("invalid query")
+
+const (
+	DeviceLogEventOnline   = "online"
+	DeviceLogEventOffline  = "offline"
+	DeviceLogEventActivate = "activate"
+	DeviceLogEventReset    = "reset"
+	DeviceLogEventCommand  = "command"
+	DeviceLogEventUpgrade  = "upgrade"
+	DeviceLogEventReport   = "report"
+)
+
+var DefaultDeviceLogEvents = []string{
+	DeviceLogEventOnline,
+	DeviceLogEventOffline,
+	DeviceLogEventCommand,
+	DeviceLogEventReport,
+}
+
+type DeviceLogQuery struct {
+	EventTypes []string
+	StartTime  time.Time
+	EndTime    time.Time
+	Size       int
+	Cursor     string
+}
+
+type DeviceLog struct {
+	EventType string    `json:"event_type"`
+	EventTime time.Time `json:"event_time"`
+	EventFrom string    `json:"event_from,omitempty"`
+	Code      string    `json:"code,omitempty"`
+	Value     any       `json:"value,omitempty"`
+}
+
+type DeviceLogPage struct {
+	Logs       []DeviceLog
+	NextCursor string
+	HasNext    bool
+}
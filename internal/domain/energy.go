@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrDeviceNotMetering      = errors.New("device does not report energy consumption")
+	ErrStatisticsNotSupported = errors.New("tuya energy statistics not supported for device")
+)
+
+const (
+	EnergyGranularityHour  = "hour"
+	EnergyGranularityDay   = "day"
+	EnergyGranularityMonth = "month"
+)
+
+const (
+	EnergySourceTuya     = "tuya"
+	EnergySourceRecorded = "recorded"
+)
+
+type EnergyQuery struct {
+	Granularity string
+	Start       time.Time
+	End         time.Time
+}
+
+type EnergyPoint struct {
+	Period time.Time `json:"period"`
+	KWh    float64   `json:"kwh"`
+}
+
+type EnergyReport struct {
+	DeviceID string        `json:"device_id,omitempty"`
+	HomeID   string        `json:"home_id,omitempty"`
+	Source   string        `json:"source,omitempty"`
+	TotalKWh float64       `json:"total_kwh"`
+	Points   []EnergyPoint `json:"points"`
+}
+
+type EnergySummary struct {
+	Granularity string         `json:"granularity"`
+	Start       time.Time      `json:"start"`
+	End         time.Time      `json:"end"`
+	Homes       []EnergyReport `json:"homes"`
+	Devices     []EnergyReport `json:"devices"`
+}
+
+type PowerSample struct {
+	DeviceID   string
+	PowerWatts float64
+	RecordedAt time.Time
+}
@@ -0,0 +1,17 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrGroupNotFound = errors.New("device group not found")
+
+type DeviceGroup struct {
+	ID        string    `json:"id"`
+	OwnerID   string    `json:"-"`
+	Name      string    `json:"name"`
+	DeviceIDs []string  `json:"device_ids"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
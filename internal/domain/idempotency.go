@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+type IdempotencyRecord struct {
+	OwnerID      string
+	Key          string
+	Fingerprint  string
+	StatusCode   int
+	ResponseBody []byte
+	CompletedAt  *time.Time
+}
+
+func (r IdempotencyRecord) Completed() bool {
+	return r.CompletedAt != nil
+}
@@ -0,0 +1,68 @@
+package domain
+
+import "errors"
+
+var (
+	ErrRemoteNotFound      = errors.New("remote not found on hub")
+	ErrNotAirConditioner   = errors.New("remote is not an air conditioner")
+	ErrInvalidRemoteAction = errors.New("invalid remote action")
+)
+
+// RemoteCategoryAirConditioner is Tuya's infrared category for air
+// conditioners, which take whole-state commands instead of key presses.
+const RemoteCategoryAirConditioner = 5
+
+// Remote is a virtual infrared remote learned or configured on a hub.
+type Remote struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	CategoryID  int    `json:"category_id"`
+	BrandID     int    `json:"brand_id"`
+	BrandName   string `json:"brand_name,omitempty"`
+	RemoteIndex int    `json:"remote_index"`
+}
+
+type RemoteKey struct {
+	Key         string `json:"key"`
+	KeyID       int    `json:"key_id"`
+	Name        string `json:"name"`
+	StandardKey bool   `json:"standard_key"`
+}
+
+// RemoteKeyPress names a key by Key or, for learned keys, by KeyID.
+type RemoteKeyPress struct {
+	Key   string `json:"key,omitempty"`
+	KeyID int    `json:"key_id,omitempty"`
+}
+
+const (
+	ACModeCool = "cool"
+	ACModeHeat = "heat"
+	ACModeAuto = "auto"
+	ACModeFan  = "fan"
+	ACModeDry  = "dry"
+)
+
+const (
+	ACFanAuto   = "auto"
+	ACFanLow    = "low"
+	ACFanMedium = "medium"
+	ACFanHigh   = "high"
+)
+
+// Infrared air conditioners accept set points in whole degrees Celsius
+// within this range.
+const (
+	MinACTemperature = 16
+	MaxACTemperature = 30
+)
+
+// ACState is the state last sent to an infrared air conditioner; the
+// remote cannot read it back from the unit. In commands, fields left out keep
+// their current value.
+type ACState struct {
+	Power       *bool  `json:"power,omitempty"`
+	Mode        string `json:"mode,omitempty"`
+	Temperature *int   `json:"temperature,omitempty"`
+	Fan         string `json:"fan,omitempty"`
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrTenantNotFound = errors.New("tenant not found")
+	ErrInvalidTenant  = errors.New("invalid tenant")
+)
+
+// Tenant is a white-label customer with its own Tuya cloud project. The
+// access secret is stored encrypted and never leaves the API.
+type Tenant struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	AccessID   string     `json:"access_id"`
+	BaseURL    string     `json:"base_url"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+}
+
+type TuyaCredentials struct {
+	AccessID     string
+	AccessSecret string
+	BaseURL      string
+}
@@ -1,6 +1,35 @@
 package domain
 
+import "errors"
+
 const (
 	TuyaDevicesEndpoint = "/v1.0/iot-03/devices"
 	TuyaUserEndpoint    = "/v1.0/users"
+	TuyaHomesEndpoint   = "/v1.0/homes"
+
+	TuyaCloudDevicesEndpoint = "/v1.0/devices"
+	TuyaInfraredsEndpoint    = "/v2.0/infrareds"
+)
+
+var ErrInvalidRegion = errors.New("invalid tuya region")
+
+const (
+	TuyaRegionChina  = "cn"
+	TuyaRegionUS     = "us"
+	TuyaRegionEurope = "eu"
+	TuyaRegionIndia  = "in"
 )
+
+// TuyaRegionBaseURLs maps each Tuya data center to its OpenAPI endpoint. An
+// account without a region uses the deployment's configured base URL.
+var TuyaRegionBaseURLs = map[string]string{
+	TuyaRegionChina:  "https://openapi.tuyacn.com",
+	TuyaRegionUS:     "https://openapi.tuyaus.com",
+	TuyaRegionEurope: "https://openapi.tuyaeu.com",
+	TuyaRegionIndia:  "https://openapi.tuyain.com",
+}
+
+func ValidTuyaRegion(region string) bool {
+	_, ok := TuyaRegionBaseURLs[region]
+	return ok
+}
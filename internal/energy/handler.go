@@ -0,0 +1,152 @@
+package energy
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/pkg/api"
+	"github.com/go-chi/chi/v5"
+)
+
+type Service interface {
+	Summary(ctx context.Context, userID string, query domain.EnergyQuery) (domain.EnergySummary, error)
+	GetDevice(ctx context.Context, userID string, deviceID string, query domain.EnergyQuery) (domain.EnergyReport, error)
+}
+
+type Handler struct {
+	svc Service
+}
+
+func NewHandler(svc Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+func (h *Handler) Summary(w http.ResponseWriter, r *http.Request) {
+	userID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	query, ok := parseQuery(w, r)
+	if !ok {
+		return
+	}
+
+	summary, err := h.svc.Summary(r.Context(), userID, query)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		writeCSV(w, "energy.csv", summary.Devices)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Energy consumption retrieved successfully", summary, nil))
+}
+
+func (h *Handler) GetDevice(w http.ResponseWriter, r *http.Request) {
+	userID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	deviceID := chi.URLParam(r, "deviceId")
+	if deviceID == "" {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Missing deviceId", nil))
+		return
+	}
+
+	query, ok := parseQuery(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.svc.GetDevice(r.Context(), userID, deviceID, query)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		writeCSV(w, fmt.Sprintf("energy-%s.csv", deviceID), []domain.EnergyReport{report})
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Device energy consumption retrieved successfully", report, map[string]any{
+		"granularity": query.Granularity,
+	}))
+}
+
+func parseQuery(w http.ResponseWriter, r *http.Request) (domain.EnergyQuery, bool) {
+	q := r.URL.Query()
+	query := domain.EnergyQuery{Granularity: q.Get("granularity")}
+
+	var err error
+	if start := q.Get("start"); start != "" {
+		query.Start, err = time.Parse(time.RFC3339, start)
+		if err != nil {
+			api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid start, expected RFC3339", nil))
+			return query, false
+		}
+	}
+
+	if end := q.Get("end"); end != "" {
+		query.End, err = time.Parse(time.RFC3339, end)
+		if err != nil {
+			api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid end, expected RFC3339", nil))
+			return query, false
+		}
+	}
+
+	return query, true
+}
+
+func writeCSV(w http.ResponseWriter, filename string, reports []domain.EnergyReport) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	rows := [][]string{{"device_id", "home_id", "period", "kwh", "source"}}
+	for _, report := range reports {
+		for _, p := range report.Points {
+			rows = append(rows, []string{
+				report.DeviceID,
+				report.HomeID,
+				p.Period.Format(time.RFC3339),
+				strconv.FormatFloat(p.KWh, 'f', 3, 64),
+				report.Source,
+			})
+		}
+	}
+
+	if err := cw.WriteAll(rows); err != nil {
+		log.Printf("failed to write csv response: %v", err)
+	}
+}
+
+func respondError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrAccountNotLinked):
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "No Tuya App Account is linked to the user", nil))
+	case errors.Is(err, domain.ErrDeviceNotOwned):
+		api.Respond(w, http.StatusForbidden, api.NewErrorResponse("FORBIDDEN", "Device does not belong to user", nil))
+	case errors.Is(err, domain.ErrDeviceNotMetering):
+		api.Respond(w, http.StatusUnprocessableEntity, api.NewErrorResponse("NOT_METERING", "Device does not report energy consumption", nil))
+	case errors.Is(err, domain.ErrInvalidQuery):
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", err.Error(), nil))
+	default:
+		api.Respond(w, http.StatusBadGateway, api.NewErrorResponse("UPSTREAM_ERROR", err.Error(), nil))
+	}
+}
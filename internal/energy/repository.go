@@ -0,0 +1,48 @@
+package energy
+
+import (
+	"context"
+	"time"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type repository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRepository(pool *pgxpool.Pool) *repository {
+	return &repository{pool: pool}
+}
+
+func (r *repository) InsertSamples(ctx context.Context, samples []domain.PowerSample) error {
+	batch := &pgx.Batch{}
+	query := `INSERT INTO device_power_samples (device_id, power_watts, recorded_at) VALUES ($1, $2, $3)`
+	for _, s := range samples {
+		batch.Queue(query, s.DeviceID, s.PowerWatts, s.RecordedAt)
+	}
+	return r.pool.SendBatch(ctx, batch).Close()
+}
+
+func (r *repository) ListSamples(ctx context.Context, deviceID string, from, to time.Time) ([]domain.PowerSample, error) {
+	query := `SELECT device_id, power_watts, recorded_at FROM device_power_samples WHERE device_id = $1 AND recorded_at >= $2 AND recorded_at < $3 ORDER BY recorded_at`
+
+	rows, err := r.pool.Query(ctx, query, deviceID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []domain.PowerSample
+	for rows.Next() {
+		var s domain.PowerSample
+		if err := rows.Scan(&s.DeviceID, &s.PowerWatts, &s.RecordedAt); err != nil {
+			return nil, err
+		}
+		samples = append(samples, s)
+	}
+
+	return samples, rows.Err()
+}
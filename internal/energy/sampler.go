@@ -0,0 +1,62 @@
+package energy
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/pkg/api"
+)
+
+// AllAccountLister returns the linked Tuya accounts of every user.
+type AllAccountLister func(ctx context.Context) ([]domain.TuyaAccount, error)
+
+type StatusRecorder func(ctx context.Context, devices []domain.Device) error
+
+// Sampler records the power of every metering device once per sampleInterval,
+// so the recorded-energy fallback does not depend on clients listing devices.
+type Sampler struct {
+	listAccounts AllAccountLister
+	listDevices  DeviceLister
+	record       StatusRecorder
+}
+
+func NewSampler(listAccounts AllAccountLister, listDevices DeviceLister, record StatusRecorder) *Sampler {
+	return &Sampler{listAccounts: listAccounts, listDevices: listDevices, record: record}
+}
+
+func (s *Sampler) Run(ctx context.Context) {
+	ticker := time.NewTicker(sampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sample(ctx)
+		}
+	}
+}
+
+func (s *Sampler) sample(ctx context.Context) {
+	accounts, err := s.listAccounts(ctx)
+	if err != nil {
+		log.Printf("Warning: failed to list accounts for power sampling: %v", err)
+		return
+	}
+
+	for _, acc := range accounts {
+		accCtx := api.NewContextWithRegion(api.NewContextWithTenantID(ctx, acc.TenantID), acc.Region)
+
+		devices, err := s.listDevices(accCtx, acc.TuyaUID)
+		if err != nil {
+			log.Printf("Warning: failed to list devices of account %s for power sampling: %v", acc.ID, err)
+			continue
+		}
+		if err := s.record(accCtx, devices); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package energy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/pkg/api"
+)
+
+const (
+	curPowerCode = "cur_power"
+
+	// cur_power is reported by Tuya metering plugs in tenths of a watt.
+	powerScale = 10
+
+	sampleInterval = time.Minute
+	maxSampleGap   = time.Hour
+)
+
+var defaultRanges = map[string]time.Duration{
+	domain.EnergyGranularityHour:  24 * time.Hour,
+	domain.EnergyGranularityDay:   30 * 24 * time.Hour,
+	domain.EnergyGranularityMonth: 365 * 24 * time.Hour,
+}
+
+var maxRanges = map[string]time.Duration{
+	domain.EnergyGranularityHour:  7 * 24 * time.Hour,
+	domain.EnergyGranularityDay:   366 * 24 * time.Hour,
+	domain.EnergyGranularityMonth: 5 * 366 * 24 * time.Hour,
+}
+
+// TuyaAccountLister returns the user's linked Tuya accounts, or
+// domain.ErrAccountNotLinked when there are none.
+type TuyaAccountLister func(ctx context.Context, userID string) ([]domain.TuyaAccount, error)
+
+type DeviceLister func(ctx context.Context, tuyaUID string) ([]domain.Device, error)
+
+type TuyaIoTClient interface {
+	GetStatistics(ctx context.Context, deviceID string, query domain.EnergyQuery) ([]domain.EnergyPoint, error)
+}
+
+type Repository interface {
+	InsertSamples(ctx context.Context, samples []domain.PowerSample) error
+	ListSamples(ctx context.Context, deviceID string, from, to time.Time) ([]domain.PowerSample, error)
+}
+
+type service struct {
+	listAccounts TuyaAccountLister
+	listDevices  DeviceLister
+	tuya         TuyaIoTClient
+	repo         Repository
+	mu           sync.Mutex
+	lastRecorded map[string]time.Time
+}
+
+func NewService(listAccounts TuyaAccountLister, listDevices DeviceLister, tuya TuyaIoTClient, repo Repository) *service {
+	return &service{
+		listAccounts: listAccounts,
+		listDevices:  listDevices,
+		tuya:         tuya,
+		repo:         repo,
+		lastRecorded: make(map[string]time.Time),
+	}
+}
+
+func (s *service) RecordStatus(ctx context.Context, devices []domain.Device) error {
+	now := time.Now().UTC()
+
+	s.mu.Lock()
+	// Entries older than sampleInterval no longer suppress a sample, so
+	// dropping them keeps the map to the devices seen in the last interval.
+	for id, last := range s.lastRecorded {
+		if now.Sub(last) >= sampleInterval {
+			delete(s.lastRecorded, id)
+		}
+	}
+
+	var samples []domain.PowerSample
+	for _, d := range devices {
+		power, ok := currentPower(d)
+		if !ok {
+			continue
+		}
+		if last, ok := s.lastRecorded[d.ID]; ok && now.Sub(last) < sampleInterval {
+			continue
+		}
+		s.lastRecorded[d.ID] = now
+		samples = append(samples, domain.PowerSample{DeviceID: d.ID, PowerWatts: power, RecordedAt: now})
+	}
+	s.mu.Unlock()
+
+	if len(samples) == 0 {
+		return nil
+	}
+
+	if err := s.repo.InsertSamples(ctx, samples); err != nil {
+		return fmt.Errorf("failed to record power samples: %w", err)
+	}
+	return nil
+}
+
+func (s *service) Summary(ctx context.Context, userID string, query domain.EnergyQuery) (domain.EnergySummary, error) {
+	query, err := normalizeQuery(query)
+	if err != nil {
+		return domain.EnergySummary{}, err
+	}
+
+	devices, err := s.userDevices(ctx, userID)
+	if err != nil {
+		return domain.EnergySummary{}, err
+	}
+
+	var metering []domain.Device
+	for _, d := range devices {
+		if _, ok := currentPower(d); ok {
+			metering = append(metering, d)
+		}
+	}
+
+	reports := make([]domain.EnergyReport, len(metering))
+	errs := make([]error, len(metering))
+	var wg sync.WaitGroup
+	for i, d := range metering {
+		wg.Add(1)
+		go func(i int, d domain.Device) {
+			defer wg.Done()
+			reports[i], errs[i] = s.deviceReport(ctx, d, query)
+		}(i, d)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return domain.EnergySummary{}, err
+	}
+
+	homes := make(map[string]map[time.Time]float64)
+	for _, report := range reports {
+		if homes[report.HomeID] == nil {
+			homes[report.HomeID] = make(map[time.Time]float64)
+		}
+		for _, p := range report.Points {
+			homes[report.HomeID][p.Period] += p.KWh
+		}
+	}
+
+	homeReports := make([]domain.EnergyReport, 0, len(homes))
+	for homeID, values := range homes {
+		report := buildReport(query, values)
+		report.HomeID = homeID
+		homeReports = append(homeReports, report)
+	}
+	sort.Slice(homeReports, func(i, j int) bool { return homeReports[i].HomeID < homeReports[j].HomeID })
+
+	return domain.EnergySummary{
+		Granularity: query.Granularity,
+		Start:       query.Start,
+		End:         query.End,
+		Homes:       homeReports,
+		Devices:     reports,
+	}, nil
+}
+
+func (s *service) GetDevice(ctx context.Context, userID string, deviceID string, query domain.EnergyQuery) (domain.EnergyReport, error) {
+	query, err := normalizeQuery(query)
+	if err != nil {
+		return domain.EnergyReport{}, err
+	}
+
+	devices, err := s.userDevices(ctx, userID)
+	if err != nil {
+		return domain.EnergyReport{}, err
+	}
+
+	for _, d := range devices {
+		if d.ID != deviceID {
+			continue
+		}
+		if _, ok := currentPower(d); !ok {
+			return domain.EnergyReport{}, domain.ErrDeviceNotMetering
+		}
+		return s.deviceReport(ctx, d, query)
+	}
+
+	return domain.EnergyReport{}, domain.ErrDeviceNotOwned
+}
+
+func (s *service) userDevices(ctx context.Context, userID string) ([]domain.Device, error) {
+	accounts, err := s.listAccounts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []domain.Device
+	for _, acc := range accounts {
+		listed, err := s.listDevices(api.NewContextWithRegion(ctx, acc.Region), acc.TuyaUID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list devices: %w", err)
+		}
+		for _, d := range listed {
+			d.Region = acc.Region
+			devices = append(devices, d)
+		}
+	}
+	return devices, nil
+}
+
+// deviceReport uses Tuya's statistics, falling back to recorded power samples
+// only for devices or projects where Tuya does not offer them.
+func (s *service) deviceReport(ctx context.Context, device domain.Device, query domain.EnergyQuery) (domain.EnergyReport, error) {
+	values := make(map[time.Time]float64)
+	source := domain.EnergySourceTuya
+
+	points, err := s.tuya.GetStatistics(api.NewContextWithRegion(ctx, device.Region), device.ID, query)
+	switch {
+	case err == nil:
+		for _, p := range points {
+			values[truncate(p.Period, query.Granularity)] += p.KWh
+		}
+	case errors.Is(err, domain.ErrStatisticsNotSupported):
+		source = domain.EnergySourceRecorded
+		if values, err = s.recordedEnergy(ctx, device.ID, query); err != nil {
+			return domain.EnergyReport{}, fmt.Errorf("failed to compute recorded energy for device %s: %w", device.ID, err)
+		}
+	default:
+		return domain.EnergyReport{}, fmt.Errorf("failed to get energy statistics for device %s: %w", device.ID, err)
+	}
+
+	report := buildReport(query, values)
+	report.DeviceID = device.ID
+	report.HomeID = device.HomeID
+	report.Source = source
+	return report, nil
+}
+
+func (s *service) recordedEnergy(ctx context.Context, deviceID string, query domain.EnergyQuery) (map[time.Time]float64, error) {
+	samples, err := s.repo.ListSamples(ctx, deviceID, query.Start.Add(-maxSampleGap), query.End)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[time.Time]float64)
+	for i := 1; i < len(samples); i++ {
+		prev, curr := samples[i-1], samples[i]
+		elapsed := curr.RecordedAt.Sub(prev.RecordedAt)
+		if elapsed <= 0 || elapsed > maxSampleGap || prev.RecordedAt.Before(query.Start) {
+			continue
+		}
+		kwh := (prev.PowerWatts + curr.PowerWatts) / 2 * elapsed.Hours() / 1000
+		values[truncate(prev.RecordedAt, query.Granularity)] += kwh
+	}
+	return values, nil
+}
+
+func normalizeQuery(query domain.EnergyQuery) (domain.EnergyQuery, error) {
+	if query.Granularity == "" {
+		query.Granularity = domain.EnergyGranularityDay
+	}
+
+	maxRange, ok := maxRanges[query.Granularity]
+	if !ok {
+		return query, fmt.Errorf("%w: unknown granularity %q", domain.ErrInvalidQuery, query.Granularity)
+	}
+
+	if query.End.IsZero() {
+		query.End = time.Now()
+	}
+	if query.Start.IsZero() {
+		query.Start = query.End.Add(-defaultRanges[query.Granularity])
+	}
+
+	query.Start = truncate(query.Start, query.Granularity)
+	query.End = query.End.UTC()
+
+	if !query.Start.Before(query.End) {
+		return query, fmt.Errorf("%w: start must be before end", domain.ErrInvalidQuery)
+	}
+	if query.End.Sub(query.Start) > maxRange {
+		return query, fmt.Errorf("%w: range exceeds %s for %s granularity", domain.ErrInvalidQuery, maxRange, query.Granularity)
+	}
+
+	return query, nil
+}
+
+func buildReport(query domain.EnergyQuery, values map[time.Time]float64) domain.EnergyReport {
+	report := domain.EnergyReport{Points: []domain.EnergyPoint{}}
+	for period := query.Start; period.Before(query.End); period = next(period, query.Granularity) {
+		kwh := roundKWh(values[period])
+		report.Points = append(report.Points, domain.EnergyPoint{Period: period, KWh: kwh})
+		report.TotalKWh += kwh
+	}
+	report.TotalKWh = roundKWh(report.TotalKWh)
+	return report
+}
+
+func currentPower(device domain.Device) (float64, bool) {
+	for _, dp := range device.Status {
+		if dp.Code != curPowerCode {
+			continue
+		}
+		if v, ok := dp.Value.(float64); ok {
+			return v / powerScale, true
+		}
+	}
+	return 0, false
+}
+
+func truncate(t time.Time, granularity string) time.Time {
+	t = t.UTC()
+	switch granularity {
+	case domain.EnergyGranularityHour:
+		return t.Truncate(time.Hour)
+	case domain.EnergyGranularityMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+func next(t time.Time, granularity string) time.Time {
+	switch granularity {
+	case domain.EnergyGranularityHour:
+		return t.Add(time.Hour)
+	case domain.EnergyGranularityMonth:
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+func roundKWh(kwh float64) float64 {
+	return math.Round(kwh*1000) / 1000
+}
@@ -0,0 +1,90 @@
+package energy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/internal/tuya"
+)
+
+const addEleCode = "add_ele"
+
+// Tuya error codes meaning statistics are unavailable for the device or the
+// cloud project, as opposed to a failed request.
+var statisticsUnsupportedCodes = map[int]bool{
+	2008:     true, // command or value not supported
+	28841101: true, // API not subscribed
+	28841105: true, // project not authorized for the API
+}
+
+type TuyaClient interface {
+	Send(ctx context.Context, req *tuya.Request) (json.RawMessage, string, error)
+}
+
+type statisticsWindow struct {
+	segment  string
+	startKey string
+	endKey   string
+	layout   string
+}
+
+var statisticsWindows = map[string]statisticsWindow{
+	domain.EnergyGranularityHour:  {segment: "hours", startKey: "start_hour", endKey: "end_hour", layout: "2006010215"},
+	domain.EnergyGranularityDay:   {segment: "days", startKey: "start_day", endKey: "end_day", layout: "20060102"},
+	domain.EnergyGranularityMonth: {segment: "months", startKey: "start_month", endKey: "end_month", layout: "200601"},
+}
+
+type tuyaIoTClient struct {
+	client TuyaClient
+}
+
+func NewTuyaIoTClient(client TuyaClient) TuyaIoTClient {
+	return &tuyaIoTClient{client: client}
+}
+
+func (c *tuyaIoTClient) GetStatistics(ctx context.Context, deviceID string, query domain.EnergyQuery) ([]domain.EnergyPoint, error) {
+	window, ok := statisticsWindows[query.Granularity]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown granularity %q", domain.ErrInvalidQuery, query.Granularity)
+	}
+
+	req := tuya.NewRequest(http.MethodGet, fmt.Sprintf("%s/%s/statistics/%s", domain.TuyaCloudDevicesEndpoint, deviceID, window.segment)).
+		WithQuery("code", addEleCode).
+		WithQuery(window.startKey, query.Start.UTC().Format(window.layout)).
+		WithQuery(window.endKey, query.End.UTC().Format(window.layout))
+
+	result, _, err := c.client.Send(ctx, req)
+	if err != nil {
+		var tuyaErr *tuya.Error
+		if errors.As(err, &tuyaErr) && statisticsUnsupportedCodes[tuyaErr.Code] {
+			return nil, fmt.Errorf("%w: %v", domain.ErrStatisticsNotSupported, err)
+		}
+		return nil, err
+	}
+
+	var stats map[string]map[string]string
+	if err := json.Unmarshal(result, &stats); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal energy statistics: %w", err)
+	}
+
+	points := make([]domain.EnergyPoint, 0, len(stats[window.segment]))
+	for key, raw := range stats[window.segment] {
+		period, err := time.Parse(window.layout, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse statistics period %q: %w", key, err)
+		}
+		kwh, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse statistics value %q: %w", raw, err)
+		}
+		points = append(points, domain.EnergyPoint{Period: period, KWh: kwh})
+	}
+
+	return points, nil
+}
@@ -0,0 +1,207 @@
+package group
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/pkg/api"
+	"github.com/go-chi/chi/v5"
+)
+
+type Service interface {
+	List(ctx context.Context, userID string) ([]domain.DeviceGroup, error)
+	Get(ctx context.Context, userID string, groupID string) (domain.DeviceGroup, error)
+	Create(ctx context.Context, userID string, name string, deviceIDs []string) (domain.DeviceGroup, error)
+	Update(ctx context.Context, userID string, groupID string, name string, deviceIDs []string) (domain.DeviceGroup, error)
+	Delete(ctx context.Context, userID string, groupID string) error
+	SendCommands(ctx context.Context, userID string, groupID string, commands []domain.DataPoint) (map[string]domain.CommandResult, error)
+}
+
+type Handler struct {
+	svc Service
+}
+
+func NewHandler(svc Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+type groupRequest struct {
+	Name      string   `json:"name"`
+	DeviceIDs []string `json:"device_ids"`
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	groups, err := h.svc.List(r.Context(), userID)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Groups retrieved successfully", groups, nil))
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	group, err := h.svc.Get(r.Context(), userID, chi.URLParam(r, "groupId"))
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Group retrieved successfully", group, nil))
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	req, ok := decodeGroupRequest(w, r)
+	if !ok {
+		return
+	}
+
+	group, err := h.svc.Create(r.Context(), userID, req.Name, req.DeviceIDs)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusCreated, api.NewSuccessResponse("Group created successfully", group, nil))
+}
+
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	req, ok := decodeGroupRequest(w, r)
+	if !ok {
+		return
+	}
+
+	group, err := h.svc.Update(r.Context(), userID, chi.URLParam(r, "groupId"), req.Name, req.DeviceIDs)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Group updated successfully", group, nil))
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	if err := h.svc.Delete(r.Context(), userID, chi.URLParam(r, "groupId")); err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Group deleted successfully", nil, nil))
+}
+
+func (h *Handler) SendCommands(w http.ResponseWriter, r *http.Request) {
+	userID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	var req struct {
+		Commands []domain.DataPoint `json:"commands"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid request body", nil))
+		return
+	}
+
+	if len(req.Commands) == 0 {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Commands cannot be empty", nil))
+		return
+	}
+
+	results, err := h.svc.SendCommands(r.Context(), userID, chi.URLParam(r, "groupId"), req.Commands)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	succeeded := 0
+	for _, result := range results {
+		if result.Success {
+			succeeded++
+		}
+	}
+
+	meta := map[string]int{
+		"total":     len(results),
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+	}
+
+	switch {
+	case succeeded == len(results):
+		api.Respond(w, http.StatusOK, api.NewSuccessResponse("Commands sent successfully", results, meta))
+	case succeeded == 0:
+		api.Respond(w, http.StatusBadGateway, api.Response{Code: "UPSTREAM_ERROR", Message: "Commands failed for every device in the group", Data: results, Meta: meta})
+	default:
+		api.Respond(w, http.StatusMultiStatus, api.NewPartialSuccessResponse("Commands sent to some devices in the group", results, meta))
+	}
+}
+
+func decodeGroupRequest(w http.ResponseWriter, r *http.Request) (groupRequest, bool) {
+	var req groupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid request body", nil))
+		return req, false
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Name cannot be empty", nil))
+		return req, false
+	}
+
+	if req.DeviceIDs == nil {
+		req.DeviceIDs = []string{}
+	}
+
+	return req, true
+}
+
+func respondError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrAccountNotLinked):
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "No Tuya App Account is linked to the user", nil))
+	case errors.Is(err, domain.ErrGroupNotFound):
+		api.Respond(w, http.StatusNotFound, api.NewErrorResponse("NOT_FOUND", "Group not found", nil))
+	case errors.Is(err, domain.ErrDeviceNotOwned):
+		api.Respond(w, http.StatusForbidden, api.NewErrorResponse("FORBIDDEN", err.Error(), nil))
+	default:
+		api.Respond(w, http.StatusInternalServerError, api.NewErrorResponse("INTERNAL_ERROR", err.Error(), nil))
+	}
+}
@@ -0,0 +1,165 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const invalidTextRepresentation = "22P02"
+
+// tenantScope matches rows of the tenant in the given placeholder, where an
+// empty tenant ID selects the deployment's default project.
+const tenantScope = `tenant_id IS NOT DISTINCT FROM NULLIF(%s, '')::uuid`
+
+const selectGroups = `
+	SELECT g.id, g.owner_id, g.name, g.created_at, g.updated_at,
+	       COALESCE(array_agg(m.device_id ORDER BY m.device_id) FILTER (WHERE m.device_id IS NOT NULL), '{}')
+	FROM device_groups g
+	LEFT JOIN device_group_members m ON m.group_id = g.id
+	WHERE g.tenant_id IS NOT DISTINCT FROM NULLIF($1, '')::uuid AND g.owner_id = $2 AND g.deleted_at IS NULL`
+
+type repository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRepository(pool *pgxpool.Pool) *repository {
+	return &repository{pool: pool}
+}
+
+func (r *repository) List(ctx context.Context, tenantID, ownerID string) ([]domain.DeviceGroup, error) {
+	query := selectGroups + ` GROUP BY g.id ORDER BY g.name`
+
+	rows, err := r.pool.Query(ctx, query, tenantID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []domain.DeviceGroup{}
+	for rows.Next() {
+		g, err := scanGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+
+	return groups, rows.Err()
+}
+
+func (r *repository) Get(ctx context.Context, tenantID, ownerID, groupID string) (domain.DeviceGroup, error) {
+	query := selectGroups + ` AND g.id = $3 GROUP BY g.id`
+
+	g, err := scanGroup(r.pool.QueryRow(ctx, query, tenantID, ownerID, groupID))
+	if err != nil {
+		if isNotFound(err) {
+			return domain.DeviceGroup{}, domain.ErrGroupNotFound
+		}
+		return domain.DeviceGroup{}, err
+	}
+
+	return g, nil
+}
+
+func (r *repository) Create(ctx context.Context, tenantID string, group domain.DeviceGroup) (domain.DeviceGroup, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return domain.DeviceGroup{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `INSERT INTO device_groups (tenant_id, owner_id, name) VALUES (NULLIF($1, '')::uuid, $2, $3) RETURNING id, created_at, updated_at`
+	if err := tx.QueryRow(ctx, query, tenantID, group.OwnerID, group.Name).Scan(&group.ID, &group.CreatedAt, &group.UpdatedAt); err != nil {
+		return domain.DeviceGroup{}, err
+	}
+
+	if err := replaceMembers(ctx, tx, group.ID, group.DeviceIDs); err != nil {
+		return domain.DeviceGroup{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return domain.DeviceGroup{}, err
+	}
+
+	return group, nil
+}
+
+func (r *repository) Update(ctx context.Context, tenantID string, group domain.DeviceGroup) (domain.DeviceGroup, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return domain.DeviceGroup{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `UPDATE device_groups SET name = $4, updated_at = NOW() WHERE ` + fmt.Sprintf(tenantScope, "$1") + ` AND owner_id = $2 AND id = $3 AND deleted_at IS NULL RETURNING created_at, updated_at`
+	if err := tx.QueryRow(ctx, query, tenantID, group.OwnerID, group.ID, group.Name).Scan(&group.CreatedAt, &group.UpdatedAt); err != nil {
+		if isNotFound(err) {
+			return domain.DeviceGroup{}, domain.ErrGroupNotFound
+		}
+		return domain.DeviceGroup{}, err
+	}
+
+	if err := replaceMembers(ctx, tx, group.ID, group.DeviceIDs); err != nil {
+		return domain.DeviceGroup{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return domain.DeviceGroup{}, err
+	}
+
+	return group, nil
+}
+
+func (r *repository) Delete(ctx context.Context, tenantID, ownerID, groupID string) error {
+	query := `UPDATE device_groups SET deleted_at = NOW() WHERE ` + fmt.Sprintf(tenantScope, "$1") + ` AND owner_id = $2 AND id = $3 AND deleted_at IS NULL`
+
+	tag, err := r.pool.Exec(ctx, query, tenantID, ownerID, groupID)
+	if err != nil {
+		if isNotFound(err) {
+			return domain.ErrGroupNotFound
+		}
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrGroupNotFound
+	}
+
+	return nil
+}
+
+func replaceMembers(ctx context.Context, tx pgx.Tx, groupID string, deviceIDs []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM device_group_members WHERE group_id = $1`, groupID); err != nil {
+		return err
+	}
+
+	if len(deviceIDs) == 0 {
+		return nil
+	}
+
+	query := `INSERT INTO device_group_members (group_id, device_id) SELECT $1, unnest($2::text[]) ON CONFLICT DO NOTHING`
+	_, err := tx.Exec(ctx, query, groupID, deviceIDs)
+	return err
+}
+
+func scanGroup(row pgx.Row) (domain.DeviceGroup, error) {
+	var g domain.DeviceGroup
+	err := row.Scan(&g.ID, &g.OwnerID, &g.Name, &g.CreatedAt, &g.UpdatedAt, &g.DeviceIDs)
+	return g, err
+}
+
+// isNotFound treats malformed group IDs the same as missing rows so callers
+// cannot distinguish between the two.
+func isNotFound(err error) bool {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == invalidTextRepresentation
+}
@@ -0,0 +1,185 @@
+package group
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/pkg/api"
+)
+
+const maxConcurrentCommands = 10
+
+// TuyaAccountLister returns the user's linked Tuya accounts, or
+// domain.ErrAccountNotLinked when there are none.
+type TuyaAccountLister func(ctx context.Context, userID string) ([]domain.TuyaAccount, error)
+
+type CommandAuditor func(ctx context.Context, entry domain.CommandAudit)
+
+type TuyaIoTClient interface {
+	SendCommands(ctx context.Context, deviceID string, commands any) (json.RawMessage, string, error)
+	List(ctx context.Context, tuyaUID string) ([]domain.Device, error)
+}
+
+type Repository interface {
+	List(ctx context.Context, tenantID, ownerID string) ([]domain.DeviceGroup, error)
+	Get(ctx context.Context, tenantID, ownerID, groupID string) (domain.DeviceGroup, error)
+	Create(ctx context.Context, tenantID string, group domain.DeviceGroup) (domain.DeviceGroup, error)
+	Update(ctx context.Context, tenantID string, group domain.DeviceGroup) (domain.DeviceGroup, error)
+	Delete(ctx context.Context, tenantID, ownerID, groupID string) error
+}
+
+type service struct {
+	repo         Repository
+	listAccounts TuyaAccountLister
+	tuya         TuyaIoTClient
+	audit        CommandAuditor
+}
+
+func NewService(repo Repository, listAccounts TuyaAccountLister, tuya TuyaIoTClient, audit CommandAuditor) *service {
+	return &service{repo: repo, listAccounts: listAccounts, tuya: tuya, audit: audit}
+}
+
+func (s *service) List(ctx context.Context, userID string) ([]domain.DeviceGroup, error) {
+	return s.repo.List(ctx, api.GetTenantIDFromContext(ctx), userID)
+}
+
+func (s *service) Get(ctx context.Context, userID string, groupID string) (domain.DeviceGroup, error) {
+	return s.repo.Get(ctx, api.GetTenantIDFromContext(ctx), userID, groupID)
+}
+
+func (s *service) Create(ctx context.Context, userID string, name string, deviceIDs []string) (domain.DeviceGroup, error) {
+	if err := s.verifyOwnership(ctx, userID, deviceIDs); err != nil {
+		return domain.DeviceGroup{}, err
+	}
+
+	return s.repo.Create(ctx, api.GetTenantIDFromContext(ctx), domain.DeviceGroup{
+		OwnerID:   userID,
+		Name:      name,
+		DeviceIDs: deviceIDs,
+	})
+}
+
+func (s *service) Update(ctx context.Context, userID string, groupID string, name string, deviceIDs []string) (domain.DeviceGroup, error) {
+	if err := s.verifyOwnership(ctx, userID, deviceIDs); err != nil {
+		return domain.DeviceGroup{}, err
+	}
+
+	return s.repo.Update(ctx, api.GetTenantIDFromContext(ctx), domain.DeviceGroup{
+		ID:        groupID,
+		OwnerID:   userID,
+		Name:      name,
+		DeviceIDs: deviceIDs,
+	})
+}
+
+func (s *service) Delete(ctx context.Context, userID string, groupID string) error {
+	return s.repo.Delete(ctx, api.GetTenantIDFromContext(ctx), userID, groupID)
+}
+
+func (s *service) SendCommands(ctx context.Context, userID string, groupID string, commands []domain.DataPoint) (map[string]domain.CommandResult, error) {
+	group, err := s.repo.Get(ctx, api.GetTenantIDFromContext(ctx), userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	owned, err := s.ownedDeviceIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]domain.CommandResult, len(group.DeviceIDs))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentCommands)
+
+	for _, deviceID := range group.DeviceIDs {
+		acc, ok := owned[deviceID]
+		if !ok {
+			results[deviceID] = domain.CommandResult{DeviceID: deviceID, Status: http.StatusForbidden, Error: domain.ErrDeviceNotOwned.Error()}
+			continue
+		}
+
+		wg.Add(1)
+		go func(deviceID string, acc domain.TuyaAccount) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result := domain.CommandResult{DeviceID: deviceID}
+			start := time.Now()
+			raw, tid, err := s.tuya.SendCommands(api.NewContextWithRegion(ctx, acc.Region), deviceID, commands)
+
+			entry := domain.CommandAudit{
+				OwnerID:   userID,
+				TuyaUID:   acc.TuyaUID,
+				DeviceID:  deviceID,
+				Commands:  commands,
+				Source:    domain.CommandSourceGroup,
+				Success:   err == nil,
+				Result:    raw,
+				Tid:       tid,
+				LatencyMs: time.Since(start).Milliseconds(),
+			}
+
+			if err != nil {
+				entry.Error = err.Error()
+				result.Status = http.StatusBadGateway
+				result.Error = err.Error()
+			} else {
+				result.Status = http.StatusOK
+				result.Success = true
+				result.Result = raw
+			}
+			s.audit(ctx, entry)
+
+			mu.Lock()
+			results[deviceID] = result
+			mu.Unlock()
+		}(deviceID, acc)
+	}
+
+	wg.Wait()
+	return results, nil
+}
+
+func (s *service) verifyOwnership(ctx context.Context, userID string, deviceIDs []string) error {
+	owned, err := s.ownedDeviceIDs(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range deviceIDs {
+		if _, ok := owned[id]; !ok {
+			return fmt.Errorf("%w: %s", domain.ErrDeviceNotOwned, id)
+		}
+	}
+	return nil
+}
+
+// ownedDeviceIDs maps every device across the user's linked accounts to the
+// account it belongs to.
+func (s *service) ownedDeviceIDs(ctx context.Context, userID string) (map[string]domain.TuyaAccount, error) {
+	accounts, err := s.listAccounts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	owned := make(map[string]domain.TuyaAccount)
+	for _, acc := range accounts {
+		devices, err := s.tuya.List(api.NewContextWithRegion(ctx, acc.Region), acc.TuyaUID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify device ownership: %w", err)
+		}
+		for _, d := range devices {
+			if _, ok := owned[d.ID]; !ok {
+				owned[d.ID] = acc
+			}
+		}
+	}
+	return owned, nil
+}
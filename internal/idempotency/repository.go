@@ -0,0 +1,79 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// tenantScope matches rows of the tenant in the given placeholder, where an
+// empty tenant ID selects the deployment's default project.
+const tenantScope = `tenant_id IS NOT DISTINCT FROM NULLIF(%s, '')::uuid`
+
+type repository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRepository(pool *pgxpool.Pool) *repository {
+	return &repository{pool: pool}
+}
+
+// Reserve claims key for ownerID within the tenant. When the key is already
+// held by an unexpired record, that record is returned with reserved set to
+// false.
+func (r *repository) Reserve(ctx context.Context, tenantID, ownerID, key, fingerprint string, ttl time.Duration) (domain.IdempotencyRecord, bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return domain.IdempotencyRecord{}, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	deleteExpired := `DELETE FROM idempotency_keys WHERE ` + fmt.Sprintf(tenantScope, "$1") + ` AND owner_id = $2 AND key = $3 AND expires_at < NOW()`
+	if _, err := tx.Exec(ctx, deleteExpired, tenantID, ownerID, key); err != nil {
+		return domain.IdempotencyRecord{}, false, err
+	}
+
+	insert := `
+		INSERT INTO idempotency_keys (tenant_id, owner_id, key, fingerprint, expires_at)
+		VALUES (NULLIF($1, '')::uuid, $2, $3, $4, $5)
+		ON CONFLICT ((COALESCE(tenant_id, '00000000-0000-0000-0000-000000000000')), owner_id, key) DO NOTHING`
+	tag, err := tx.Exec(ctx, insert, tenantID, ownerID, key, fingerprint, time.Now().Add(ttl))
+	if err != nil {
+		return domain.IdempotencyRecord{}, false, err
+	}
+
+	rec := domain.IdempotencyRecord{OwnerID: ownerID, Key: key, Fingerprint: fingerprint}
+	reserved := tag.RowsAffected() == 1
+
+	if !reserved {
+		var statusCode *int
+		query := `SELECT fingerprint, status_code, response_body, completed_at FROM idempotency_keys WHERE ` + fmt.Sprintf(tenantScope, "$1") + ` AND owner_id = $2 AND key = $3`
+		if err := tx.QueryRow(ctx, query, tenantID, ownerID, key).Scan(&rec.Fingerprint, &statusCode, &rec.ResponseBody, &rec.CompletedAt); err != nil {
+			return domain.IdempotencyRecord{}, false, err
+		}
+		if statusCode != nil {
+			rec.StatusCode = *statusCode
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return domain.IdempotencyRecord{}, false, err
+	}
+
+	return rec, reserved, nil
+}
+
+func (r *repository) Complete(ctx context.Context, tenantID, ownerID, key string, statusCode int, body []byte) error {
+	query := `UPDATE idempotency_keys SET status_code = $4, response_body = $5, completed_at = NOW() WHERE ` + fmt.Sprintf(tenantScope, "$1") + ` AND owner_id = $2 AND key = $3`
+	_, err := r.pool.Exec(ctx, query, tenantID, ownerID, key, statusCode, body)
+	return err
+}
+
+func (r *repository) Release(ctx context.Context, tenantID, ownerID, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE ` + fmt.Sprintf(tenantScope, "$1") + ` AND owner_id = $2 AND key = $3 AND completed_at IS NULL`
+	_, err := r.pool.Exec(ctx, query, tenantID, ownerID, key)
+	return err
+}
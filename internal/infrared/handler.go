@@ -0,0 +1,158 @@
+package infrared
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/pkg/api"
+	"github.com/go-chi/chi/v5"
+)
+
+type Service interface {
+	ListRemotes(ctx context.Context, userID string, hubID string) ([]domain.Remote, error)
+	ListKeys(ctx context.Context, userID string, hubID string, remoteID string) ([]domain.RemoteKey, error)
+	SendKey(ctx context.Context, userID string, hubID string, remoteID string, key domain.RemoteKeyPress) (json.RawMessage, error)
+	GetACState(ctx context.Context, userID string, hubID string, remoteID string) (domain.ACState, error)
+	SetACState(ctx context.Context, userID string, hubID string, remoteID string, update domain.ACState) (domain.ACState, error)
+}
+
+type Handler struct {
+	svc Service
+}
+
+func NewHandler(svc Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+func (h *Handler) ListRemotes(w http.ResponseWriter, r *http.Request) {
+	userID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	hubID := chi.URLParam(r, "deviceId")
+	if hubID == "" {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Missing deviceId", nil))
+		return
+	}
+
+	remotes, err := h.svc.ListRemotes(r.Context(), userID, hubID)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Remotes retrieved successfully", remotes, nil))
+}
+
+func (h *Handler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	userID, hubID, remoteID, ok := remotePath(w, r)
+	if !ok {
+		return
+	}
+
+	keys, err := h.svc.ListKeys(r.Context(), userID, hubID, remoteID)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Remote keys retrieved successfully", keys, nil))
+}
+
+func (h *Handler) SendKey(w http.ResponseWriter, r *http.Request) {
+	userID, hubID, remoteID, ok := remotePath(w, r)
+	if !ok {
+		return
+	}
+
+	var req domain.RemoteKeyPress
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid request body", nil))
+		return
+	}
+
+	result, err := h.svc.SendKey(r.Context(), userID, hubID, remoteID, req)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Key press sent successfully", result, nil))
+}
+
+func (h *Handler) GetACState(w http.ResponseWriter, r *http.Request) {
+	userID, hubID, remoteID, ok := remotePath(w, r)
+	if !ok {
+		return
+	}
+
+	state, err := h.svc.GetACState(r.Context(), userID, hubID, remoteID)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("AC state retrieved successfully", state, nil))
+}
+
+func (h *Handler) SetACState(w http.ResponseWriter, r *http.Request) {
+	userID, hubID, remoteID, ok := remotePath(w, r)
+	if !ok {
+		return
+	}
+
+	var req domain.ACState
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid request body", nil))
+		return
+	}
+
+	state, err := h.svc.SetACState(r.Context(), userID, hubID, remoteID, req)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("AC state sent successfully", state, nil))
+}
+
+func remotePath(w http.ResponseWriter, r *http.Request) (string, string, string, bool) {
+	userID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return "", "", "", false
+	}
+
+	hubID := chi.URLParam(r, "deviceId")
+	remoteID := chi.URLParam(r, "remoteId")
+	if hubID == "" || remoteID == "" {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Missing deviceId or remoteId", nil))
+		return "", "", "", false
+	}
+
+	return userID, hubID, remoteID, true
+}
+
+func respondError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrAccountNotLinked):
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "No Tuya App Account is linked to the user", nil))
+	case errors.Is(err, domain.ErrDeviceNotOwned):
+		api.Respond(w, http.StatusForbidden, api.NewErrorResponse("FORBIDDEN", "Device does not belong to user", nil))
+	case errors.Is(err, domain.ErrInsufficientRole):
+		api.Respond(w, http.StatusForbidden, api.NewErrorResponse("FORBIDDEN", "User role does not allow this action on the device", nil))
+	case errors.Is(err, domain.ErrRemoteNotFound):
+		api.Respond(w, http.StatusNotFound, api.NewErrorResponse("NOT_FOUND", "Remote not found on hub", nil))
+	case errors.Is(err, domain.ErrNotAirConditioner):
+		api.Respond(w, http.StatusUnprocessableEntity, api.NewErrorResponse("UNSUPPORTED_CAPABILITY", "Remote is not an air conditioner", nil))
+	case errors.Is(err, domain.ErrInvalidRemoteAction):
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", err.Error(), nil))
+	default:
+		api.Respond(w, http.StatusBadGateway, api.NewErrorResponse("UPSTREAM_ERROR", err.Error(), nil))
+	}
+}
@@ -0,0 +1,255 @@
+package infrared
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/pkg/api"
+)
+
+// HubAccessVerifier checks that userID holds at least role on the hub and
+// returns it with the Tuya UID of its account. Remotes are virtual devices
+// reached only through their hub, so access to a remote is access to its hub.
+type HubAccessVerifier func(ctx context.Context, userID, hubID, role string) (domain.Device, string, error)
+
+type CommandAuditor func(ctx context.Context, entry domain.CommandAudit)
+
+type TuyaIoTClient interface {
+	ListRemotes(ctx context.Context, hubID string) ([]domain.Remote, error)
+	ListKeys(ctx context.Context, hubID, remoteID string) ([]domain.RemoteKey, error)
+	SendKey(ctx context.Context, hubID string, remote domain.Remote, key domain.RemoteKeyPress) (json.RawMessage, string, error)
+	GetACState(ctx context.Context, hubID, remoteID string) (domain.ACState, error)
+	SetACState(ctx context.Context, hubID, remoteID string, state domain.ACState) (json.RawMessage, string, error)
+}
+
+type service struct {
+	verifyHub HubAccessVerifier
+	tuya      TuyaIoTClient
+	audit     CommandAuditor
+}
+
+func NewService(verifyHub HubAccessVerifier, tuya TuyaIoTClient, audit CommandAuditor) *service {
+	return &service{
+		verifyHub: verifyHub,
+		tuya:      tuya,
+		audit:     audit,
+	}
+}
+
+func (s *service) ListRemotes(ctx context.Context, userID string, hubID string) ([]domain.Remote, error) {
+	ctx, _, err := s.hub(ctx, userID, hubID, domain.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	remotes, err := s.tuya.ListRemotes(ctx, hubID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remotes: %w", err)
+	}
+	return remotes, nil
+}
+
+func (s *service) ListKeys(ctx context.Context, userID string, hubID string, remoteID string) ([]domain.RemoteKey, error) {
+	ctx, _, err := s.hub(ctx, userID, hubID, domain.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.remote(ctx, hubID, remoteID); err != nil {
+		return nil, err
+	}
+
+	keys, err := s.tuya.ListKeys(ctx, hubID, remoteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *service) SendKey(ctx context.Context, userID string, hubID string, remoteID string, key domain.RemoteKeyPress) (json.RawMessage, error) {
+	if key.Key == "" && key.KeyID == 0 {
+		return nil, fmt.Errorf("%w: key or key_id is required", domain.ErrInvalidRemoteAction)
+	}
+
+	ctx, tuyaUID, err := s.hub(ctx, userID, hubID, domain.RoleOperator)
+	if err != nil {
+		return nil, err
+	}
+
+	remote, err := s.remote(ctx, hubID, remoteID)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	result, tid, err := s.tuya.SendKey(ctx, hubID, remote, key)
+	s.record(ctx, domain.CommandAudit{
+		OwnerID:  userID,
+		TuyaUID:  tuyaUID,
+		DeviceID: hubID,
+		RemoteID: remoteID,
+		Commands: keyPressPoints(key),
+		Result:   result,
+		Tid:      tid,
+	}, start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send key press: %w", err)
+	}
+	return result, nil
+}
+
+func (s *service) GetACState(ctx context.Context, userID string, hubID string, remoteID string) (domain.ACState, error) {
+	ctx, _, err := s.hub(ctx, userID, hubID, domain.RoleViewer)
+	if err != nil {
+		return domain.ACState{}, err
+	}
+
+	if _, err := s.airConditioner(ctx, hubID, remoteID); err != nil {
+		return domain.ACState{}, err
+	}
+
+	state, err := s.tuya.GetACState(ctx, hubID, remoteID)
+	if err != nil {
+		return domain.ACState{}, fmt.Errorf("failed to get AC state: %w", err)
+	}
+	return state, nil
+}
+
+// SetACState applies the given fields on top of the AC's last known state and
+// sends the result, returning the state that was sent.
+func (s *service) SetACState(ctx context.Context, userID string, hubID string, remoteID string, update domain.ACState) (domain.ACState, error) {
+	if err := validateACState(update); err != nil {
+		return domain.ACState{}, err
+	}
+
+	ctx, tuyaUID, err := s.hub(ctx, userID, hubID, domain.RoleOperator)
+	if err != nil {
+		return domain.ACState{}, err
+	}
+
+	if _, err := s.airConditioner(ctx, hubID, remoteID); err != nil {
+		return domain.ACState{}, err
+	}
+
+	state, err := s.tuya.GetACState(ctx, hubID, remoteID)
+	if err != nil {
+		// A full update does not need the current state.
+		fmt.Printf("Warning: failed to get state of AC %s: %v\n", remoteID, err)
+	}
+
+	if update.Power != nil {
+		state.Power = update.Power
+	}
+	if update.Mode != "" {
+		state.Mode = update.Mode
+	}
+	if update.Temperature != nil {
+		state.Temperature = update.Temperature
+	}
+	if update.Fan != "" {
+		state.Fan = update.Fan
+	}
+	if state.Power == nil || state.Mode == "" || state.Temperature == nil || state.Fan == "" {
+		return domain.ACState{}, fmt.Errorf("%w: current AC state is unknown, so power, mode, temperature and fan are required", domain.ErrInvalidRemoteAction)
+	}
+
+	start := time.Now()
+	result, tid, err := s.tuya.SetACState(ctx, hubID, remoteID, state)
+	s.record(ctx, domain.CommandAudit{
+		OwnerID:  userID,
+		TuyaUID:  tuyaUID,
+		DeviceID: hubID,
+		RemoteID: remoteID,
+		Commands: acStatePoints(state),
+		Result:   result,
+		Tid:      tid,
+	}, start, err)
+	if err != nil {
+		return domain.ACState{}, fmt.Errorf("failed to set AC state: %w", err)
+	}
+	return state, nil
+}
+
+// hub verifies access to the hub and returns a context routed to its region
+// together with the Tuya UID of the hub's account.
+func (s *service) hub(ctx context.Context, userID, hubID, role string) (context.Context, string, error) {
+	hub, tuyaUID, err := s.verifyHub(ctx, userID, hubID, role)
+	if err != nil {
+		return nil, "", err
+	}
+	return api.NewContextWithRegion(ctx, hub.Region), tuyaUID, nil
+}
+
+// record completes entry with the outcome of a command sent through the hub
+// and writes it to the command audit log.
+func (s *service) record(ctx context.Context, entry domain.CommandAudit, start time.Time, err error) {
+	entry.Source = domain.CommandSourceInfrared
+	entry.Success = err == nil
+	entry.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	s.audit(ctx, entry)
+}
+
+// keyPressPoints records a key press in the audit log's data point form.
+func keyPressPoints(key domain.RemoteKeyPress) []domain.DataPoint {
+	if key.KeyID != 0 {
+		return []domain.DataPoint{{Code: "key_id", Value: key.KeyID}}
+	}
+	return []domain.DataPoint{{Code: "key", Value: key.Key}}
+}
+
+// acStatePoints records the complete AC state that was sent.
+func acStatePoints(state domain.ACState) []domain.DataPoint {
+	return []domain.DataPoint{
+		{Code: "power", Value: *state.Power},
+		{Code: "mode", Value: state.Mode},
+		{Code: "temperature", Value: *state.Temperature},
+		{Code: "fan", Value: state.Fan},
+	}
+}
+
+// remote returns the hub's remote with remoteID, so a remote cannot be driven
+// through a hub it does not belong to.
+func (s *service) remote(ctx context.Context, hubID, remoteID string) (domain.Remote, error) {
+	remotes, err := s.tuya.ListRemotes(ctx, hubID)
+	if err != nil {
+		return domain.Remote{}, fmt.Errorf("failed to list remotes: %w", err)
+	}
+
+	i := slices.IndexFunc(remotes, func(r domain.Remote) bool { return r.ID == remoteID })
+	if i < 0 {
+		return domain.Remote{}, domain.ErrRemoteNotFound
+	}
+	return remotes[i], nil
+}
+
+func (s *service) airConditioner(ctx context.Context, hubID, remoteID string) (domain.Remote, error) {
+	remote, err := s.remote(ctx, hubID, remoteID)
+	if err != nil {
+		return domain.Remote{}, err
+	}
+	if remote.CategoryID != domain.RemoteCategoryAirConditioner {
+		return domain.Remote{}, domain.ErrNotAirConditioner
+	}
+	return remote, nil
+}
+
+func validateACState(state domain.ACState) error {
+	switch {
+	case state.Power == nil && state.Mode == "" && state.Temperature == nil && state.Fan == "":
+		return fmt.Errorf("%w: at least one of power, mode, temperature or fan is required", domain.ErrInvalidRemoteAction)
+	case state.Mode != "" && !slices.Contains(acModes, state.Mode):
+		return fmt.Errorf("%w: mode must be one of %v", domain.ErrInvalidRemoteAction, acModes)
+	case state.Fan != "" && !slices.Contains(acFans, state.Fan):
+		return fmt.Errorf("%w: fan must be one of %v", domain.ErrInvalidRemoteAction, acFans)
+	case state.Temperature != nil && (*state.Temperature < domain.MinACTemperature || *state.Temperature > domain.MaxACTemperature):
+		return fmt.Errorf("%w: temperature must be between %d and %d", domain.ErrInvalidRemoteAction, domain.MinACTemperature, domain.MaxACTemperature)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package infrared
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/internal/tuya"
+	"github.com/avagenc/zee-api/pkg/api"
+)
+
+type TuyaClient interface {
+	Send(ctx context.Context, req *tuya.Request) (json.RawMessage, string, error)
+}
+
+// Tuya numbers AC modes and fan speeds by their position in these lists.
+var (
+	acModes = []string{domain.ACModeCool, domain.ACModeHeat, domain.ACModeAuto, domain.ACModeFan, domain.ACModeDry}
+	acFans  = []string{domain.ACFanAuto, domain.ACFanLow, domain.ACFanMedium, domain.ACFanHigh}
+)
+
+type tuyaIoTClient struct {
+	client TuyaClient
+}
+
+func NewTuyaIoTClient(client TuyaClient) TuyaIoTClient {
+	return &tuyaIoTClient{client: client}
+}
+
+func (c *tuyaIoTClient) ListRemotes(ctx context.Context, hubID string) ([]domain.Remote, error) {
+	req := tuya.NewRequest(http.MethodGet, fmt.Sprintf("%s/%s/remotes", domain.TuyaInfraredsEndpoint, hubID))
+	result, _, err := c.client.Send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []struct {
+		RemoteID    string `json:"remote_id"`
+		RemoteName  string `json:"remote_name"`
+		CategoryID  int    `json:"category_id"`
+		BrandID     int    `json:"brand_id"`
+		BrandName   string `json:"brand_name"`
+		RemoteIndex int    `json:"remote_index"`
+	}
+	if err := json.Unmarshal(result, &raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal remotes: %w", err)
+	}
+
+	remotes := make([]domain.Remote, len(raw))
+	for i, r := range raw {
+		remotes[i] = domain.Remote{
+			ID:          r.RemoteID,
+			Name:        r.RemoteName,
+			CategoryID:  r.CategoryID,
+			BrandID:     r.BrandID,
+			BrandName:   r.BrandName,
+			RemoteIndex: r.RemoteIndex,
+		}
+	}
+	return remotes, nil
+}
+
+func (c *tuyaIoTClient) ListKeys(ctx context.Context, hubID, remoteID string) ([]domain.RemoteKey, error) {
+	req := tuya.NewRequest(http.MethodGet, fmt.Sprintf("%s/%s/remotes/%s/keys", domain.TuyaInfraredsEndpoint, hubID, remoteID))
+	result, _, err := c.client.Send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw struct {
+		KeyList []struct {
+			Key         string `json:"key"`
+			KeyID       int    `json:"key_id"`
+			KeyName     string `json:"key_name"`
+			StandardKey bool   `json:"standard_key"`
+		} `json:"key_list"`
+	}
+	if err := json.Unmarshal(result, &raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal remote keys: %w", err)
+	}
+
+	keys := make([]domain.RemoteKey, len(raw.KeyList))
+	for i, k := range raw.KeyList {
+		keys[i] = domain.RemoteKey{Key: k.Key, KeyID: k.KeyID, Name: k.KeyName, StandardKey: k.StandardKey}
+	}
+	return keys, nil
+}
+
+func (c *tuyaIoTClient) SendKey(ctx context.Context, hubID string, remote domain.Remote, key domain.RemoteKeyPress) (json.RawMessage, string, error) {
+	payload := map[string]any{"category_id": remote.CategoryID}
+	if key.KeyID != 0 {
+		payload["key_id"] = key.KeyID
+	} else {
+		payload["key"] = key.Key
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal key press: %w", err)
+	}
+
+	req := tuya.NewRequest(http.MethodPost, fmt.Sprintf("%s/%s/remotes/%s/command", domain.TuyaInfraredsEndpoint, hubID, remote.ID)).
+		WithBody(body)
+	api.MarkUpstreamWrite(ctx)
+	return c.client.Send(ctx, req)
+}
+
+func (c *tuyaIoTClient) GetACState(ctx context.Context, hubID, remoteID string) (domain.ACState, error) {
+	req := tuya.NewRequest(http.MethodGet, fmt.Sprintf("%s/%s/remotes/%s/ac/status", domain.TuyaInfraredsEndpoint, hubID, remoteID))
+	result, _, err := c.client.Send(ctx, req)
+	if err != nil {
+		return domain.ACState{}, err
+	}
+
+	// Values arrive as strings or numbers depending on the hub.
+	var raw map[string]any
+	if err := json.Unmarshal(result, &raw); err != nil {
+		return domain.ACState{}, fmt.Errorf("failed to unmarshal AC status: %w", err)
+	}
+
+	var state domain.ACState
+	if power, ok := acValue(raw, "power"); ok {
+		on := power == 1
+		state.Power = &on
+	}
+	if mode, ok := acValue(raw, "mode"); ok && mode >= 0 && mode < len(acModes) {
+		state.Mode = acModes[mode]
+	}
+	if temp, ok := acValue(raw, "temp"); ok {
+		state.Temperature = &temp
+	}
+	if wind, ok := acValue(raw, "wind"); ok && wind >= 0 && wind < len(acFans) {
+		state.Fan = acFans[wind]
+	}
+	return state, nil
+}
+
+// SetACState sends a complete AC state, which is how infrared air
+// conditioners are driven: every field must be set.
+func (c *tuyaIoTClient) SetACState(ctx context.Context, hubID, remoteID string, state domain.ACState) (json.RawMessage, string, error) {
+	power := 0
+	if *state.Power {
+		power = 1
+	}
+
+	body, err := json.Marshal(map[string]int{
+		"power": power,
+		"mode":  slices.Index(acModes, state.Mode),
+		"temp":  *state.Temperature,
+		"wind":  slices.Index(acFans, state.Fan),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal AC state: %w", err)
+	}
+
+	req := tuya.NewRequest(http.MethodPost, fmt.Sprintf("%s/%s/air-conditioners/%s/scenes-command", domain.TuyaInfraredsEndpoint, hubID, remoteID)).
+		WithBody(body)
+	api.MarkUpstreamWrite(ctx)
+	return c.client.Send(ctx, req)
+}
+
+func acValue(raw map[string]any, key string) (int, bool) {
+	switch v := raw[key].(type) {
+	case float64:
+		return int(v), true
+	case string:
+		n, err := strconv.Atoi(v)
+		return n, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,226 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformed        = errors.New("malformed token")
+	ErrUnsupportedAlg   = errors.New("unsupported signing algorithm")
+	ErrInvalidSignature = errors.New("invalid token signature")
+	ErrExpired          = errors.New("token is expired")
+	ErrNotYetValid      = errors.New("token is not valid yet")
+	ErrInvalidIssuer    = errors.New("invalid token issuer")
+	ErrInvalidAudience  = errors.New("invalid token audience")
+	ErrMissingUserClaim = errors.New("token is missing the user claim")
+)
+
+type Options struct {
+	JWKSFile      string
+	PublicKeyFile string
+	HMACSecret    string
+	Issuer        string
+	Audience      string
+	UserClaim     string
+	Leeway        time.Duration
+}
+
+type Verifier struct {
+	keys      []key
+	issuer    string
+	audience  string
+	userClaim string
+	leeway    time.Duration
+}
+
+func NewVerifier(opts Options) (*Verifier, error) {
+	v := &Verifier{
+		issuer:    opts.Issuer,
+		audience:  opts.Audience,
+		userClaim: opts.UserClaim,
+		leeway:    opts.Leeway,
+	}
+	if v.userClaim == "" {
+		v.userClaim = "sub"
+	}
+
+	if opts.JWKSFile != "" {
+		keys, err := loadJWKSFile(opts.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		v.keys = append(v.keys, keys...)
+	}
+
+	if opts.PublicKeyFile != "" {
+		pub, err := loadPublicKeyFile(opts.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		v.keys = append(v.keys, key{material: pub})
+	}
+
+	if opts.HMACSecret != "" {
+		v.keys = append(v.keys, key{material: []byte(opts.HMACSecret)})
+	}
+
+	if len(v.keys) == 0 {
+		return nil, errors.New("no JWT verification keys configured")
+	}
+
+	return v, nil
+}
+
+// Verify checks the token signature and registered claims and returns the
+// value of the configured user claim.
+func (v *Verifier) Verify(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", ErrMalformed
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return "", ErrMalformed
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrMalformed
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	if err := v.verifySignature(header.Alg, header.Kid, signed, signature); err != nil {
+		return "", err
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return "", ErrMalformed
+	}
+
+	if err := v.validateClaims(claims); err != nil {
+		return "", err
+	}
+
+	userID, ok := claims[v.userClaim].(string)
+	if !ok || userID == "" {
+		return "", ErrMissingUserClaim
+	}
+
+	return userID, nil
+}
+
+func (v *Verifier) verifySignature(alg, kid string, signed, signature []byte) error {
+	digest := sha256.Sum256(signed)
+
+	for _, k := range v.keys {
+		if kid != "" && k.id != "" && k.id != kid {
+			continue
+		}
+
+		switch material := k.material.(type) {
+		case []byte:
+			if alg != "HS256" {
+				continue
+			}
+			mac := hmac.New(sha256.New, material)
+			mac.Write(signed)
+			if hmac.Equal(mac.Sum(nil), signature) {
+				return nil
+			}
+		case *rsa.PublicKey:
+			if alg != "RS256" {
+				continue
+			}
+			if rsa.VerifyPKCS1v15(material, crypto.SHA256, digest[:], signature) == nil {
+				return nil
+			}
+		case *ecdsa.PublicKey:
+			if alg != "ES256" || len(signature) != 64 {
+				continue
+			}
+			r := new(big.Int).SetBytes(signature[:32])
+			s := new(big.Int).SetBytes(signature[32:])
+			if ecdsa.Verify(material, digest[:], r, s) {
+				return nil
+			}
+		}
+	}
+
+	switch alg {
+	case "HS256", "RS256", "ES256":
+		return ErrInvalidSignature
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedAlg, alg)
+	}
+}
+
+func (v *Verifier) validateClaims(claims map[string]any) error {
+	now := time.Now()
+
+	exp, ok := numericDate(claims["exp"])
+	if !ok || now.After(exp.Add(v.leeway)) {
+		return ErrExpired
+	}
+
+	if nbf, ok := numericDate(claims["nbf"]); ok && now.Add(v.leeway).Before(nbf) {
+		return ErrNotYetValid
+	}
+
+	if v.issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != v.issuer {
+			return ErrInvalidIssuer
+		}
+	}
+
+	if v.audience != "" && !hasAudience(claims["aud"], v.audience) {
+		return ErrInvalidAudience
+	}
+
+	return nil
+}
+
+func numericDate(v any) (time.Time, bool) {
+	n, ok := v.(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(n), 0), true
+}
+
+func hasAudience(aud any, want string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == want
+	case []any:
+		for _, a := range aud {
+			if s, ok := a.(string); ok && s == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeSegment(segment string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,190 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+const (
+	testSecret   = "test-secret"
+	testIssuer   = "https://auth.example.com"
+	testAudience = "zee-api"
+)
+
+func sign(t *testing.T, header, claims map[string]any, signer func(signed []byte) []byte) string {
+	t.Helper()
+
+	segment := func(v any) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("failed to encode token segment: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	signed := segment(header) + "." + segment(claims)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signer([]byte(signed)))
+}
+
+func hs256(secret []byte) func([]byte) []byte {
+	return func(signed []byte) []byte {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signed)
+		return mac.Sum(nil)
+	}
+}
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"sub": "user-1",
+		"iss": testIssuer,
+		"aud": testAudience,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func newTestVerifier(t *testing.T) *Verifier {
+	t.Helper()
+
+	v, err := NewVerifier(Options{HMACSecret: testSecret, Issuer: testIssuer, Audience: testAudience})
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	return v
+}
+
+func TestVerify(t *testing.T) {
+	v := newTestVerifier(t)
+	hs256Header := map[string]any{"alg": "HS256", "typ": "JWT"}
+
+	with := func(key string, value any) map[string]any {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "valid token",
+			token: sign(t, hs256Header, validClaims(), hs256([]byte(testSecret))),
+			want:  "user-1",
+		},
+		{
+			name:  "audience list",
+			token: sign(t, hs256Header, with("aud", []string{"other", testAudience}), hs256([]byte(testSecret))),
+			want:  "user-1",
+		},
+		{
+			name:    "wrong secret",
+			token:   sign(t, hs256Header, validClaims(), hs256([]byte("other-secret"))),
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "alg none",
+			token:   sign(t, map[string]any{"alg": "none"}, validClaims(), func([]byte) []byte { return nil }),
+			wantErr: ErrUnsupportedAlg,
+		},
+		{
+			name:    "alg mismatch",
+			token:   sign(t, map[string]any{"alg": "RS256"}, validClaims(), hs256([]byte(testSecret))),
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "expired",
+			token:   sign(t, hs256Header, with("exp", time.Now().Add(-time.Minute).Unix()), hs256([]byte(testSecret))),
+			wantErr: ErrExpired,
+		},
+		{
+			name:    "missing exp",
+			token:   sign(t, hs256Header, with("exp", nil), hs256([]byte(testSecret))),
+			wantErr: ErrExpired,
+		},
+		{
+			name:    "not yet valid",
+			token:   sign(t, hs256Header, with("nbf", time.Now().Add(time.Hour).Unix()), hs256([]byte(testSecret))),
+			wantErr: ErrNotYetValid,
+		},
+		{
+			name:    "wrong issuer",
+			token:   sign(t, hs256Header, with("iss", "https://evil.example.com"), hs256([]byte(testSecret))),
+			wantErr: ErrInvalidIssuer,
+		},
+		{
+			name:    "wrong audience",
+			token:   sign(t, hs256Header, with("aud", "other-api"), hs256([]byte(testSecret))),
+			wantErr: ErrInvalidAudience,
+		},
+		{
+			name:    "missing user claim",
+			token:   sign(t, hs256Header, with("sub", nil), hs256([]byte(testSecret))),
+			wantErr: ErrMissingUserClaim,
+		},
+		{
+			name:    "malformed",
+			token:   "not-a-token",
+			wantErr: ErrMalformed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := v.Verify(tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Verify() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestVerifyRejectsKeyConfusion checks that a token signed with HS256 using
+// an RSA public key as the secret is not accepted by an RSA verifier.
+func TestVerifyRejectsKeyConfusion(t *testing.T) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	v := &Verifier{keys: []key{{material: &private.PublicKey}}, userClaim: "sub"}
+
+	rs256 := func(signed []byte) []byte {
+		digest := sha256.Sum256(signed)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, private, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return signature
+	}
+
+	if got, err := v.Verify(sign(t, map[string]any{"alg": "RS256"}, validClaims(), rs256)); err != nil || got != "user-1" {
+		t.Fatalf("Verify() = %q, %v, want user-1", got, err)
+	}
+
+	public, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to encode public key: %v", err)
+	}
+	token := sign(t, map[string]any{"alg": "HS256"}, validClaims(), hs256(public))
+	if _, err := v.Verify(token); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("Verify() error = %v, want %v", err, ErrInvalidSignature)
+	}
+}
@@ -1,12 +1,20 @@
 package middleware
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net/http"
 
+	"github.com/avagenc/zee-api/internal/domain"
 	"github.com/avagenc/zee-api/pkg/api"
 )
 
-func AuthenticateAPIKey(validKey string) func(http.Handler) http.Handler {
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (domain.APIKey, error)
+}
+
+func AuthenticateAPIKey(auth APIKeyAuthenticator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			key := r.Header.Get("x-avagenc-api-key")
@@ -15,8 +23,43 @@ func AuthenticateAPIKey(validKey string) func(http.Handler) http.Handler {
 				return
 			}
 
-			if key != validKey {
-				api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Invalid API key", nil))
+			apiKey, err := auth.Authenticate(r.Context(), key)
+			if err != nil {
+				if errors.Is(err, domain.ErrInvalidAPIKey) {
+					api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Invalid API key", nil))
+					return
+				}
+				log.Printf("failed to authenticate api key: %v", err)
+				api.Respond(w, http.StatusInternalServerError, api.NewErrorResponse("INTERNAL_ERROR", "Failed to authenticate API key", nil))
+				return
+			}
+
+			ctx := api.NewContextWithClient(r.Context(), api.Client{
+				ID:       apiKey.ID,
+				Name:     apiKey.Name,
+				Scopes:   apiKey.Scopes,
+				TenantID: apiKey.TenantID,
+			})
+			ctx = api.NewContextWithTenantID(ctx, apiKey.TenantID)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireScope rejects requests whose API key lacks scope. It must run after
+// AuthenticateAPIKey.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client, err := api.GetClientFromContext(r.Context())
+			if err != nil {
+				api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing API key", nil))
+				return
+			}
+
+			if !client.HasScope(scope) && !client.HasScope(domain.ScopeAdmin) {
+				api.Respond(w, http.StatusForbidden, api.NewErrorResponse("FORBIDDEN", "API key lacks the "+scope+" scope", nil))
 				return
 			}
 
@@ -24,3 +67,22 @@ func AuthenticateAPIKey(validKey string) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// RequirePlatformKey rejects API keys bound to a tenant. It guards operations
+// that span tenants, such as managing the tenants themselves.
+func RequirePlatformKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, err := api.GetClientFromContext(r.Context())
+		if err != nil {
+			api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing API key", nil))
+			return
+		}
+
+		if client.TenantID != "" {
+			api.Respond(w, http.StatusForbidden, api.NewErrorResponse("FORBIDDEN", "Tenant API keys cannot perform this operation", nil))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/pkg/api"
+)
+
+const maxIdempotencyKeyLength = 255
+
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, tenantID, ownerID, key, fingerprint string, ttl time.Duration) (domain.IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, tenantID, ownerID, key string, statusCode int, body []byte) error
+	Release(ctx context.Context, tenantID, ownerID, key string) error
+}
+
+// Idempotency replays the stored response for requests carrying a previously
+// seen Idempotency-Key. It must run after RequireUserIdentity.
+func Idempotency(store IdempotencyStore, ttl time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("Idempotency-Key")
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Idempotency-Key is too long", nil))
+				return
+			}
+
+			ownerID, err := api.GetUserIDFromContext(r.Context())
+			if err != nil {
+				api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+				return
+			}
+
+			tenantID := api.GetTenantIDFromContext(r.Context())
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid request body", nil))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			fingerprint := requestFingerprint(r.Method, r.URL.Path, r.URL.Query().Encode(), body)
+
+			rec, reserved, err := store.Reserve(r.Context(), tenantID, ownerID, key, fingerprint, ttl)
+			if err != nil {
+				log.Printf("failed to reserve idempotency key: %v", err)
+				api.Respond(w, http.StatusInternalServerError, api.NewErrorResponse("INTERNAL_ERROR", "Failed to process Idempotency-Key", nil))
+				return
+			}
+
+			if !reserved {
+				switch {
+				case rec.Fingerprint != fingerprint:
+					api.Respond(w, http.StatusUnprocessableEntity, api.NewErrorResponse("IDEMPOTENCY_KEY_REUSED", "Idempotency-Key was already used with a different request", nil))
+				case !rec.Completed():
+					api.Respond(w, http.StatusConflict, api.NewErrorResponse("IDEMPOTENCY_KEY_IN_USE", "A request with this Idempotency-Key is still in progress", nil))
+				default:
+					w.Header().Set("Content-Type", "application/json")
+					w.Header().Set("Idempotent-Replayed", "true")
+					w.WriteHeader(rec.StatusCode)
+					w.Write(rec.ResponseBody)
+				}
+				return
+			}
+
+			ctx, writes := api.NewContextWithWriteTracker(r.Context())
+			rw := &recordingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, r.WithContext(ctx))
+
+			// The client may have disconnected, which is exactly when a retry
+			// will follow, so the outcome is persisted regardless. A failure
+			// is only released for retry when nothing was sent to Tuya yet: a
+			// command that timed out may still have reached the device.
+			ctx = context.WithoutCancel(ctx)
+			if rw.status >= http.StatusInternalServerError && !writes.Attempted() {
+				if err := store.Release(ctx, tenantID, ownerID, key); err != nil {
+					log.Printf("failed to release idempotency key: %v", err)
+				}
+				return
+			}
+
+			if err := store.Complete(ctx, tenantID, ownerID, key, rw.status, rw.body.Bytes()); err != nil {
+				log.Printf("failed to store idempotent response: %v", err)
+			}
+		})
+	}
+}
+
+// requestFingerprint covers the query, which selects modes such as async and
+// confirm, in canonical order so reordered parameters still match.
+func requestFingerprint(method, path, query string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + "\n" + path + "\n" + query + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *recordingResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingResponseWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
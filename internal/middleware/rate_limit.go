@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/avagenc/zee-api/pkg/api"
+)
+
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+func (l RateLimit) enabled() bool {
+	return l.Requests > 0 && l.Window > 0
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// RateLimiter is an in-memory token bucket keyed by API client and by user.
+// Each limiter instance holds its own buckets, so separate instances give
+// reads and commands independent quotas.
+type RateLimiter struct {
+	name    string
+	perKey  RateLimit
+	perUser RateLimit
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func NewRateLimiter(name string, perKey, perUser RateLimit) *RateLimiter {
+	rl := &RateLimiter{
+		name:    name,
+		perKey:  perKey,
+		perUser: perUser,
+		buckets: make(map[string]*bucket),
+	}
+	go rl.evictIdle()
+	return rl
+}
+
+func (rl *RateLimiter) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+
+		var checks []rateCheck
+		if client, err := api.GetClientFromContext(r.Context()); err == nil && rl.perKey.enabled() {
+			checks = append(checks, rateCheck{key: "key:" + client.ID, limit: rl.perKey})
+		}
+		if userID, err := api.GetUserIDFromContext(r.Context()); err == nil && rl.perUser.enabled() {
+			// User IDs are only unique within a tenant.
+			key := "user:" + api.GetTenantIDFromContext(r.Context()) + "/" + userID
+			checks = append(checks, rateCheck{key: key, limit: rl.perUser})
+		}
+
+		allowed, tightest := rl.take(checks, now)
+		if tightest != nil {
+			w.Header().Set("RateLimit-Policy", strconv.Itoa(tightest.limit.Requests)+";w="+strconv.Itoa(int(tightest.limit.Window.Seconds())))
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(tightest.limit.Requests))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(tightest.remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(tightest.reset))
+		}
+
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(tightest.retryAfter))
+			api.Respond(w, http.StatusTooManyRequests, api.NewErrorResponse("RATE_LIMITED", "Too many "+rl.name+" requests, retry later", nil))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+type rateCheck struct {
+	key        string
+	limit      RateLimit
+	remaining  int
+	reset      int
+	retryAfter int
+}
+
+// take consumes one token from every bucket only when all of them have
+// capacity, and returns the check with the least remaining capacity.
+func (rl *RateLimiter) take(checks []rateCheck, now time.Time) (bool, *rateCheck) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	allowed := true
+	for _, c := range checks {
+		if rl.refill(c.key, c.limit, now).tokens < 1 {
+			allowed = false
+		}
+	}
+
+	var tightest *rateCheck
+	for i := range checks {
+		c := &checks[i]
+		b := rl.buckets[c.key]
+		if allowed {
+			b.tokens--
+		}
+
+		rate := float64(c.limit.Requests) / c.limit.Window.Seconds()
+		c.remaining = int(math.Floor(math.Max(0, b.tokens)))
+		c.reset = int(math.Ceil((float64(c.limit.Requests) - b.tokens) / rate))
+		c.retryAfter = int(math.Ceil(math.Max(0, 1-b.tokens) / rate))
+
+		if tightest == nil || c.remaining < tightest.remaining || (c.remaining == tightest.remaining && c.retryAfter > tightest.retryAfter) {
+			tightest = c
+		}
+	}
+
+	return allowed, tightest
+}
+
+func (rl *RateLimiter) refill(key string, limit RateLimit, now time.Time) *bucket {
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Requests), updated: now}
+		rl.buckets[key] = b
+		return b
+	}
+
+	rate := float64(limit.Requests) / limit.Window.Seconds()
+	b.tokens = math.Min(float64(limit.Requests), b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+	return b
+}
+
+func (rl *RateLimiter) evictIdle() {
+	window := max(rl.perKey.Window, rl.perUser.Window)
+	if window <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(window)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		rl.mu.Lock()
+		for key, b := range rl.buckets {
+			if now.Sub(b.updated) > window {
+				delete(rl.buckets, key)
+			}
+		}
+		rl.mu.Unlock()
+	}
+}
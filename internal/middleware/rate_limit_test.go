@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/avagenc/zee-api/pkg/api"
+)
+
+func TestRateLimiterTakeRefills(t *testing.T) {
+	limit := RateLimit{Requests: 2, Window: 10 * time.Second}
+	rl := &RateLimiter{buckets: make(map[string]*bucket)}
+	start := time.Unix(1_700_000_000, 0)
+
+	tests := []struct {
+		name          string
+		at            time.Duration
+		wantAllowed   bool
+		wantRemaining int
+	}{
+		{name: "first request", at: 0, wantAllowed: true, wantRemaining: 1},
+		{name: "second request", at: 0, wantAllowed: true, wantRemaining: 0},
+		{name: "bucket empty", at: time.Second, wantAllowed: false, wantRemaining: 0},
+		{name: "one token refilled", at: 5 * time.Second, wantAllowed: true, wantRemaining: 0},
+		{name: "refill capped at capacity", at: time.Hour, wantAllowed: true, wantRemaining: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, tightest := rl.take([]rateCheck{{key: "key:a", limit: limit}}, start.Add(tt.at))
+			if allowed != tt.wantAllowed {
+				t.Errorf("allowed = %v, want %v", allowed, tt.wantAllowed)
+			}
+			if tightest.remaining != tt.wantRemaining {
+				t.Errorf("remaining = %d, want %d", tightest.remaining, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestRateLimiterTakeAllOrNothing(t *testing.T) {
+	rl := &RateLimiter{buckets: make(map[string]*bucket)}
+	now := time.Unix(1_700_000_000, 0)
+	checks := func() []rateCheck {
+		return []rateCheck{
+			{key: "key:a", limit: RateLimit{Requests: 5, Window: time.Minute}},
+			{key: "user:/u", limit: RateLimit{Requests: 1, Window: time.Minute}},
+		}
+	}
+
+	if allowed, _ := rl.take(checks(), now); !allowed {
+		t.Fatal("first request was rejected")
+	}
+
+	allowed, tightest := rl.take(checks(), now)
+	if allowed {
+		t.Fatal("request over the user limit was allowed")
+	}
+	if tightest.key != "user:/u" {
+		t.Errorf("tightest = %q, want the user bucket", tightest.key)
+	}
+	if tokens := rl.buckets["key:a"].tokens; tokens != 4 {
+		t.Errorf("key bucket has %v tokens, want 4; a rejected request must not consume it", tokens)
+	}
+}
+
+func TestRateLimiterLimit(t *testing.T) {
+	rl := NewRateLimiter("command", RateLimit{}, RateLimit{Requests: 1, Window: time.Minute})
+	handler := rl.Limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	request := func(tenantID, userID string) *httptest.ResponseRecorder {
+		ctx, err := api.NewContextWithUserID(api.NewContextWithTenantID(t.Context(), tenantID), userID)
+		if err != nil {
+			t.Fatalf("NewContextWithUserID: %v", err)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil).WithContext(ctx))
+		return rec
+	}
+
+	tests := []struct {
+		name       string
+		tenantID   string
+		userID     string
+		wantStatus int
+	}{
+		{name: "first request", tenantID: "tenant-a", userID: "user-1", wantStatus: http.StatusNoContent},
+		{name: "over the limit", tenantID: "tenant-a", userID: "user-1", wantStatus: http.StatusTooManyRequests},
+		{name: "same user id in another tenant", tenantID: "tenant-b", userID: "user-1", wantStatus: http.StatusNoContent},
+		{name: "another user", tenantID: "tenant-a", userID: "user-2", wantStatus: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := request(tt.tenantID, tt.userID)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if rec.Header().Get("RateLimit-Limit") != "1" {
+				t.Errorf("RateLimit-Limit = %q, want 1", rec.Header().Get("RateLimit-Limit"))
+			}
+			if tt.wantStatus == http.StatusTooManyRequests && rec.Header().Get("Retry-After") != "60" {
+				t.Errorf("Retry-After = %q, want 60", rec.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
@@ -2,12 +2,22 @@ package middleware
 
 import (
 	"net/http"
+	"strings"
 
+	"github.com/avagenc/zee-api/internal/domain"
 	"github.com/avagenc/zee-api/pkg/api"
 )
 
+// RequireUserIdentity takes the user from the x-user-id header. Naming any
+// user is only safe for trusted internal callers, so the API key must carry
+// the impersonation scope. It must run after AuthenticateAPIKey.
 func RequireUserIdentity(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !canImpersonate(r) {
+			api.Respond(w, http.StatusForbidden, api.NewErrorResponse("FORBIDDEN", "API key lacks the "+domain.ScopeImpersonate+" scope", nil))
+			return
+		}
+
 		userID := r.Header.Get("x-user-id")
 		if userID == "" {
 			api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
@@ -23,3 +33,54 @@ func RequireUserIdentity(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+type TokenVerifier interface {
+	Verify(token string) (string, error)
+}
+
+// RequireBearerIdentity resolves the user from a JWT bearer token. When
+// allowHeader is set, requests without an Authorization header fall back to
+// the x-user-id header, but only for callers whose API key carries the
+// impersonation scope.
+func RequireBearerIdentity(verifier TokenVerifier, allowHeader bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fallback := RequireUserIdentity(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization := r.Header.Get("Authorization")
+			if authorization == "" {
+				if allowHeader && canImpersonate(r) {
+					fallback.ServeHTTP(w, r)
+					return
+				}
+				api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing bearer token", nil))
+				return
+			}
+
+			token, ok := strings.CutPrefix(authorization, "Bearer ")
+			if !ok || token == "" {
+				api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Invalid authorization header", nil))
+				return
+			}
+
+			userID, err := verifier.Verify(token)
+			if err != nil {
+				api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Invalid bearer token", nil))
+				return
+			}
+
+			ctx, err := api.NewContextWithUserID(r.Context(), userID)
+			if err != nil {
+				api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Invalid user identity", nil))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func canImpersonate(r *http.Request) bool {
+	client, err := api.GetClientFromContext(r.Context())
+	return err == nil && client.HasScope(domain.ScopeImpersonate)
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/pkg/api"
+)
+
+func TestRequireUserIdentity(t *testing.T) {
+	handler := RequireUserIdentity(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := api.GetUserIDFromContext(r.Context())
+		if err != nil {
+			t.Errorf("user ID not set: %v", err)
+		}
+		w.Header().Set("x-resolved-user", userID)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name       string
+		scopes     []string
+		userID     string
+		wantStatus int
+	}{
+		{name: "impersonation scope", scopes: []string{domain.ScopeImpersonate}, userID: "user-1", wantStatus: http.StatusNoContent},
+		{name: "missing header", scopes: []string{domain.ScopeImpersonate}, wantStatus: http.StatusUnauthorized},
+		{name: "read-only key", scopes: []string{domain.ScopeDevicesRead}, userID: "user-1", wantStatus: http.StatusForbidden},
+		{name: "admin does not imply impersonation", scopes: []string{domain.ScopeAdmin}, userID: "user-1", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req = req.WithContext(api.NewContextWithClient(req.Context(), api.Client{ID: "key-1", Scopes: tt.scopes}))
+			if tt.userID != "" {
+				req.Header.Set("x-user-id", tt.userID)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusNoContent && rec.Header().Get("x-resolved-user") != tt.userID {
+				t.Errorf("user = %q, want %q", rec.Header().Get("x-resolved-user"), tt.userID)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package secretbox
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNoKey      = errors.New("secret key is not configured")
+	ErrUnknownKey = errors.New("sealed value uses an unknown key")
+)
+
+// magic prefixes envelope-sealed values so they can be told apart from values
+// sealed directly with a key before envelopes were introduced.
+var magic = []byte("zeb\x01")
+
+const dataKeySize = 32
+
+// Box encrypts small secrets such as tenant credentials with envelope
+// encryption: every value gets a random AES-256-GCM data key, which is wrapped
+// by the keyring's active key-encryption key. Sealed values are
+//
+//	magic | len(keyID) | keyID | len(wrapped) | wrapped data key | nonce | ciphertext
+//
+// so values sealed under a retired key still open while it stays in the
+// keyring, and Rewrap moves them to the active key without touching the data.
+type Box struct {
+	active string
+	keks   map[string]cipher.AEAD
+	index  []byte
+}
+
+// NewBox builds a Box from a keyring. An empty keyring yields a Box that
+// refuses to seal or open, so deployments without secrets need no key.
+func NewBox(ring Keyring) (*Box, error) {
+	if len(ring.Keys) == 0 {
+		return &Box{}, nil
+	}
+
+	keys, err := ring.decode()
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Box{active: ring.Active, keks: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		b.keks[id] = aead
+	}
+
+	indexKey, err := ring.indexKey(keys)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, indexKey)
+	mac.Write([]byte("zee-api blind index"))
+	b.index = mac.Sum(nil)
+
+	return b, nil
+}
+
+// Enabled reports whether the Box has a key to seal with.
+func (b *Box) Enabled() bool {
+	return b.active != ""
+}
+
+// ActiveKeyID returns the ID of the key new values are sealed under.
+func (b *Box) ActiveKeyID() string {
+	return b.active
+}
+
+func (b *Box) Seal(plaintext []byte) ([]byte, error) {
+	if !b.Enabled() {
+		return nil, ErrNoKey
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	data, err := seal(aead, plaintext, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.envelope(dataKey, data)
+}
+
+func (b *Box) Open(sealed []byte) ([]byte, error) {
+	if !b.Enabled() {
+		return nil, ErrNoKey
+	}
+
+	env, ok := parse(sealed)
+	if !ok {
+		return b.openLegacy(sealed)
+	}
+
+	dataKey, err := b.unwrap(env)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := open(aead, env.data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+	return plaintext, nil
+}
+
+// Rewrap re-seals a value's data key under the active key. It reports whether
+// the value changed; values already under the active key are returned as is.
+func (b *Box) Rewrap(sealed []byte) ([]byte, bool, error) {
+	if !b.Enabled() {
+		return nil, false, ErrNoKey
+	}
+
+	env, ok := parse(sealed)
+	if !ok {
+		plaintext, err := b.openLegacy(sealed)
+		if err != nil {
+			return nil, false, err
+		}
+		rewrapped, err := b.Seal(plaintext)
+		return rewrapped, err == nil, err
+	}
+	if env.keyID == b.active {
+		return sealed, false, nil
+	}
+
+	dataKey, err := b.unwrap(env)
+	if err != nil {
+		return nil, false, err
+	}
+	rewrapped, err := b.envelope(dataKey, env.data)
+	return rewrapped, err == nil, err
+}
+
+// Index returns a keyed hash of value for equality lookups and unique
+// constraints on encrypted columns. It is derived from the keyring's index
+// key rather than the active key, so it does not change when keys rotate.
+func (b *Box) Index(value []byte) (string, error) {
+	if !b.Enabled() {
+		return "", ErrNoKey
+	}
+
+	mac := hmac.New(sha256.New, b.index)
+	mac.Write(value)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (b *Box) envelope(dataKey, data []byte) ([]byte, error) {
+	wrapped, err := seal(b.keks[b.active], dataKey, []byte(b.active))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(magic)+2+len(b.active)+len(wrapped)+len(data))
+	out = append(out, magic...)
+	out = append(out, byte(len(b.active)))
+	out = append(out, b.active...)
+	out = append(out, byte(len(wrapped)))
+	out = append(out, wrapped...)
+	return append(out, data...), nil
+}
+
+func (b *Box) unwrap(env envelope) ([]byte, error) {
+	kek, ok := b.keks[env.keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, env.keyID)
+	}
+
+	dataKey, err := open(kek, env.wrapped, []byte(env.keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	return dataKey, nil
+}
+
+// openLegacy opens a value sealed directly with a key-encryption key. The
+// value does not record which key, so every key in the keyring is tried.
+func (b *Box) openLegacy(sealed []byte) ([]byte, error) {
+	for _, kek := range b.keks {
+		if plaintext, err := open(kek, sealed, nil); err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, errors.New("failed to decrypt secret with any configured key")
+}
+
+type envelope struct {
+	keyID   string
+	wrapped []byte
+	data    []byte
+}
+
+func parse(sealed []byte) (envelope, bool) {
+	rest, ok := bytes.CutPrefix(sealed, magic)
+	if !ok {
+		return envelope{}, false
+	}
+
+	keyID, rest, ok := field(rest)
+	if !ok {
+		return envelope{}, false
+	}
+	wrapped, data, ok := field(rest)
+	if !ok {
+		return envelope{}, false
+	}
+	return envelope{keyID: string(keyID), wrapped: wrapped, data: data}, true
+}
+
+// field splits a length-prefixed field off the front of b.
+func field(b []byte) ([]byte, []byte, bool) {
+	if len(b) == 0 || len(b) < 1+int(b[0]) {
+		return nil, nil, false
+	}
+	n := 1 + int(b[0])
+	return b[1:n], b[n:], true
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func open(aead cipher.AEAD, sealed, additional []byte) ([]byte, error) {
+	nonceSize := aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, errors.New("sealed value is too short")
+	}
+	return aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], additional)
+}
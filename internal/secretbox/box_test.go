@@ -0,0 +1,195 @@
+package secretbox
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+func newTestBox(t *testing.T, ring Keyring) *Box {
+	t.Helper()
+
+	b, err := NewBox(ring)
+	if err != nil {
+		t.Fatalf("NewBox: %v", err)
+	}
+	return b
+}
+
+func TestSealOpen(t *testing.T) {
+	b := newTestBox(t, Keyring{Active: "k1", Keys: map[string]string{"k1": testKey(1)}})
+	plaintext := []byte("tuya-uid-123")
+
+	sealed, err := b.Seal(plaintext)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if bytes.Contains(sealed, plaintext) {
+		t.Fatal("sealed value contains the plaintext")
+	}
+
+	again, err := b.Seal(plaintext)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if bytes.Equal(sealed, again) {
+		t.Error("sealing twice produced the same value")
+	}
+
+	opened, err := b.Open(sealed)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Errorf("Open() = %q, want %q", opened, plaintext)
+	}
+
+	tampered := bytes.Clone(sealed)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := b.Open(tampered); err == nil {
+		t.Error("Open() accepted a tampered value")
+	}
+}
+
+func TestOpenUnknownKey(t *testing.T) {
+	old := newTestBox(t, Keyring{Active: "k1", Keys: map[string]string{"k1": testKey(1)}})
+	sealed, err := old.Seal([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	b := newTestBox(t, Keyring{Active: "k2", Keys: map[string]string{"k2": testKey(2)}})
+	if _, err := b.Open(sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Open() error = %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestDisabledBox(t *testing.T) {
+	b := newTestBox(t, Keyring{})
+	if b.Enabled() {
+		t.Fatal("box without keys is enabled")
+	}
+	if _, err := b.Seal([]byte("secret")); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Seal() error = %v, want %v", err, ErrNoKey)
+	}
+	if _, err := b.Open([]byte("secret")); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Open() error = %v, want %v", err, ErrNoKey)
+	}
+	if _, err := b.Index([]byte("secret")); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Index() error = %v, want %v", err, ErrNoKey)
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	index := testKey(9)
+	old := newTestBox(t, Keyring{Active: "k1", Keys: map[string]string{"k1": testKey(1)}, IndexKey: index})
+	sealed, err := old.Seal([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	b := newTestBox(t, Keyring{Active: "k2", Keys: map[string]string{"k1": testKey(1), "k2": testKey(2)}, IndexKey: index})
+	rewrapped, changed, err := b.Rewrap(sealed)
+	if err != nil {
+		t.Fatalf("Rewrap: %v", err)
+	}
+	if !changed {
+		t.Fatal("Rewrap() reported no change for a value under a retired key")
+	}
+
+	current := newTestBox(t, Keyring{Active: "k2", Keys: map[string]string{"k2": testKey(2)}, IndexKey: index})
+	opened, err := current.Open(rewrapped)
+	if err != nil {
+		t.Fatalf("Open after dropping the retired key: %v", err)
+	}
+	if string(opened) != "secret" {
+		t.Errorf("Open() = %q, want secret", opened)
+	}
+
+	again, changed, err := b.Rewrap(rewrapped)
+	if err != nil {
+		t.Fatalf("Rewrap: %v", err)
+	}
+	if changed || !bytes.Equal(again, rewrapped) {
+		t.Error("Rewrap() changed a value already under the active key")
+	}
+}
+
+// TestLegacyValues checks values sealed directly with a key-encryption key,
+// before envelopes were introduced, still open and are moved to envelopes.
+func TestLegacyValues(t *testing.T) {
+	b := newTestBox(t, Keyring{Active: "k2", Keys: map[string]string{"k1": testKey(1), "k2": testKey(2)}, IndexKey: testKey(9)})
+	legacy, err := seal(b.keks["k1"], []byte("secret"), nil)
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+
+	opened, err := b.Open(legacy)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if string(opened) != "secret" {
+		t.Errorf("Open() = %q, want secret", opened)
+	}
+
+	rewrapped, changed, err := b.Rewrap(legacy)
+	if err != nil {
+		t.Fatalf("Rewrap: %v", err)
+	}
+	if env, ok := parse(rewrapped); !changed || !ok || env.keyID != "k2" {
+		t.Fatalf("Rewrap() of a legacy value = changed %v, envelope %v under %q", changed, ok, env.keyID)
+	}
+	if opened, err := b.Open(rewrapped); err != nil || string(opened) != "secret" {
+		t.Errorf("Open() of the rewrapped value = %q, %v", opened, err)
+	}
+
+	if _, err := b.Open([]byte("not sealed with any key")); err == nil {
+		t.Error("Open() accepted a value sealed with no configured key")
+	}
+}
+
+func TestIndex(t *testing.T) {
+	single := newTestBox(t, Keyring{Active: "k1", Keys: map[string]string{"k1": testKey(1)}})
+	pinned := newTestBox(t, Keyring{Active: "k1", Keys: map[string]string{"k1": testKey(1)}, IndexKey: testKey(1)})
+	rotated := newTestBox(t, Keyring{Active: "k2", Keys: map[string]string{"k1": testKey(1), "k2": testKey(2)}, IndexKey: testKey(1)})
+
+	index := func(b *Box, value string) string {
+		t.Helper()
+		got, err := b.Index([]byte(value))
+		if err != nil {
+			t.Fatalf("Index: %v", err)
+		}
+		return got
+	}
+
+	want := index(single, "uid-1")
+	if len(want) != 64 {
+		t.Errorf("Index() = %q, want 64 hex characters", want)
+	}
+	if index(single, "uid-1") != want {
+		t.Error("Index() is not deterministic")
+	}
+	if index(single, "uid-2") == want {
+		t.Error("different values share an index")
+	}
+	if got := index(pinned, "uid-1"); got != want {
+		t.Errorf("pinning the single key as index key changed the index: %s, want %s", got, want)
+	}
+	if got := index(rotated, "uid-1"); got != want {
+		t.Errorf("rotating the active key changed the index: %s, want %s", got, want)
+	}
+}
+
+func TestNewBoxRequiresIndexKeyWithSeveralKeys(t *testing.T) {
+	if _, err := NewBox(Keyring{Active: "k2", Keys: map[string]string{"k1": testKey(1), "k2": testKey(2)}}); err == nil {
+		t.Fatal("NewBox() accepted a keyring with several keys and no index key")
+	}
+	if _, err := NewBox(Keyring{Active: "k1", Keys: map[string]string{"k1": testKey(1)}, IndexKey: "short"}); err == nil {
+		t.Fatal("NewBox() accepted a malformed index key")
+	}
+}
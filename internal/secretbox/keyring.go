@@ -0,0 +1,120 @@
+package secretbox
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// DefaultKeyID names the key configured through a single SECRET_KEY.
+const DefaultKeyID = "default"
+
+// Keyring holds base64-encoded 32-byte key-encryption keys by ID. New values
+// are sealed under Active; the other keys only open existing values, which is
+// how a key is retired: add the new key, make it active, run the re-encryption
+// command, then drop the old key.
+//
+// IndexKey is the base64-encoded 32-byte key blind indexes are derived from.
+// It never rotates, so indexes stay comparable across key rotations. A
+// keyring holding a single key may omit it and derive indexes from that key;
+// before adding a second key, set IndexKey to the current key so existing
+// indexes still match.
+type Keyring struct {
+	Active   string            `json:"active"`
+	Keys     map[string]string `json:"keys"`
+	IndexKey string            `json:"index_key"`
+}
+
+// LoadKeyring builds the keyring from configuration. A key file takes
+// precedence; otherwise the single encodedKey becomes the active key under
+// keyID, or DefaultKeyID when keyID is empty. A non-empty indexKey overrides
+// the key file's index key.
+func LoadKeyring(encodedKey, keyID, keyFile, indexKey string) (Keyring, error) {
+	var ring Keyring
+	switch {
+	case keyFile != "":
+		var err error
+		if ring, err = readKeyFile(keyFile); err != nil {
+			return Keyring{}, err
+		}
+	case encodedKey == "":
+		return Keyring{}, nil
+	default:
+		if keyID == "" {
+			keyID = DefaultKeyID
+		}
+		ring = Keyring{Active: keyID, Keys: map[string]string{keyID: encodedKey}}
+	}
+
+	if indexKey != "" {
+		ring.IndexKey = indexKey
+	}
+	return ring, nil
+}
+
+// readKeyFile reads a JSON keyring such as
+//
+//	{"active": "2025-06", "keys": {"2025-01": "<base64>", "2025-06": "<base64>"}, "index_key": "<base64>"}
+func readKeyFile(path string) (Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Keyring{}, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	var ring Keyring
+	if err := json.Unmarshal(data, &ring); err != nil {
+		return Keyring{}, fmt.Errorf("failed to parse key file: %w", err)
+	}
+	if len(ring.Keys) == 0 {
+		return Keyring{}, fmt.Errorf("key file %s has no keys", path)
+	}
+	return ring, nil
+}
+
+func (k Keyring) decode() (map[string][]byte, error) {
+	if _, ok := k.Keys[k.Active]; !ok {
+		return nil, fmt.Errorf("active key %q is not in the keyring", k.Active)
+	}
+
+	keys := make(map[string][]byte, len(k.Keys))
+	for id, encoded := range k.Keys {
+		if id == "" || len(id) > 255 {
+			return nil, fmt.Errorf("invalid key ID %q", id)
+		}
+
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		keys[id] = key
+	}
+	return keys, nil
+}
+
+// indexKey returns the key blind indexes are derived from. Without a
+// dedicated index key only a single-key keyring has one that cannot change.
+func (k Keyring) indexKey(keys map[string][]byte) ([]byte, error) {
+	if k.IndexKey != "" {
+		key, err := decodeKey(k.IndexKey)
+		if err != nil {
+			return nil, fmt.Errorf("index key: %w", err)
+		}
+		return key, nil
+	}
+	if len(keys) > 1 {
+		return nil, fmt.Errorf("a keyring with several keys needs an index key")
+	}
+	return keys[k.Active], nil
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
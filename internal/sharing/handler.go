@@ -0,0 +1,127 @@
+package sharing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/pkg/api"
+	"github.com/go-chi/chi/v5"
+)
+
+type Service interface {
+	Grant(ctx context.Context, userID string, grant domain.AccessGrant) (domain.AccessGrant, error)
+	ListGranted(ctx context.Context, userID string) ([]domain.AccessGrant, error)
+	ListReceived(ctx context.Context, userID string) ([]domain.AccessGrant, error)
+	Revoke(ctx context.Context, userID string, grantID string) error
+}
+
+type Handler struct {
+	svc Service
+}
+
+func NewHandler(svc Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+func (h *Handler) ListGranted(w http.ResponseWriter, r *http.Request) {
+	userID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	grants, err := h.svc.ListGranted(r.Context(), userID)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Shares retrieved successfully", grants, nil))
+}
+
+func (h *Handler) ListReceived(w http.ResponseWriter, r *http.Request) {
+	userID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	grants, err := h.svc.ListReceived(r.Context(), userID)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Received shares retrieved successfully", grants, nil))
+}
+
+func (h *Handler) Grant(w http.ResponseWriter, r *http.Request) {
+	userID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	var req struct {
+		GranteeID    string `json:"grantee_id"`
+		ResourceType string `json:"resource_type"`
+		ResourceID   string `json:"resource_id"`
+		Role         string `json:"role"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid request body", nil))
+		return
+	}
+
+	if req.GranteeID == "" || req.ResourceType == "" || req.ResourceID == "" || req.Role == "" {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "grantee_id, resource_type, resource_id and role are required", nil))
+		return
+	}
+
+	grant, err := h.svc.Grant(r.Context(), userID, domain.AccessGrant{
+		GranteeID:    req.GranteeID,
+		ResourceType: req.ResourceType,
+		ResourceID:   req.ResourceID,
+		Role:         req.Role,
+	})
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusCreated, api.NewSuccessResponse("Access granted successfully", grant, nil))
+}
+
+func (h *Handler) Revoke(w http.ResponseWriter, r *http.Request) {
+	userID, err := api.GetUserIDFromContext(r.Context())
+	if err != nil {
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "Missing user identity", nil))
+		return
+	}
+
+	if err := h.svc.Revoke(r.Context(), userID, chi.URLParam(r, "shareId")); err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Access revoked successfully", nil, nil))
+}
+
+func respondError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrAccountNotLinked):
+		api.Respond(w, http.StatusUnauthorized, api.NewErrorResponse("UNAUTHORIZED", "No Tuya App Account is linked to the user", nil))
+	case errors.Is(err, domain.ErrDeviceNotOwned):
+		api.Respond(w, http.StatusForbidden, api.NewErrorResponse("FORBIDDEN", "Device or home does not belong to user", nil))
+	case errors.Is(err, domain.ErrGrantNotFound):
+		api.Respond(w, http.StatusNotFound, api.NewErrorResponse("NOT_FOUND", "Share not found", nil))
+	case errors.Is(err, domain.ErrInvalidGrant):
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", err.Error(), nil))
+	default:
+		api.Respond(w, http.StatusInternalServerError, api.NewErrorResponse("INTERNAL_ERROR", err.Error(), nil))
+	}
+}
@@ -0,0 +1,101 @@
+package sharing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const invalidTextRepresentation = "22P02"
+
+const grantColumns = `id, owner_id, grantee_id, resource_type, resource_id, role, created_at, updated_at`
+
+// tenantScope matches rows of the tenant in the given placeholder, where an
+// empty tenant ID selects the deployment's default project.
+const tenantScope = `tenant_id IS NOT DISTINCT FROM NULLIF(%s, '')::uuid`
+
+type repository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRepository(pool *pgxpool.Pool) *repository {
+	return &repository{pool: pool}
+}
+
+func (r *repository) Upsert(ctx context.Context, tenantID string, grant domain.AccessGrant) (domain.AccessGrant, error) {
+	query := `
+		INSERT INTO access_grants (tenant_id, owner_id, grantee_id, resource_type, resource_id, role)
+		VALUES (NULLIF($1, '')::uuid, $2, $3, $4, $5, $6)
+		ON CONFLICT ((COALESCE(tenant_id, '00000000-0000-0000-0000-000000000000')), owner_id, grantee_id, resource_type, resource_id)
+		DO UPDATE SET role = EXCLUDED.role, updated_at = NOW()
+		RETURNING ` + grantColumns
+
+	g, err := scanGrant(r.pool.QueryRow(ctx, query, tenantID, grant.OwnerID, grant.GranteeID, grant.ResourceType, grant.ResourceID, grant.Role))
+	if err != nil {
+		if isInvalidID(err) {
+			return domain.AccessGrant{}, domain.ErrInvalidGrant
+		}
+		return domain.AccessGrant{}, err
+	}
+	return g, nil
+}
+
+func (r *repository) ListByOwner(ctx context.Context, tenantID, ownerID string) ([]domain.AccessGrant, error) {
+	query := `SELECT ` + grantColumns + ` FROM access_grants WHERE ` + fmt.Sprintf(tenantScope, "$1") + ` AND owner_id = $2 ORDER BY created_at`
+	return r.query(ctx, query, tenantID, ownerID)
+}
+
+func (r *repository) ListByGrantee(ctx context.Context, tenantID, granteeID string) ([]domain.AccessGrant, error) {
+	query := `SELECT ` + grantColumns + ` FROM access_grants WHERE ` + fmt.Sprintf(tenantScope, "$1") + ` AND grantee_id = $2 ORDER BY created_at`
+	return r.query(ctx, query, tenantID, granteeID)
+}
+
+func (r *repository) Delete(ctx context.Context, tenantID, ownerID, grantID string) error {
+	query := `DELETE FROM access_grants WHERE ` + fmt.Sprintf(tenantScope, "$1") + ` AND owner_id = $2 AND id = $3`
+
+	tag, err := r.pool.Exec(ctx, query, tenantID, ownerID, grantID)
+	if err != nil {
+		if isInvalidID(err) {
+			return domain.ErrGrantNotFound
+		}
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrGrantNotFound
+	}
+	return nil
+}
+
+func (r *repository) query(ctx context.Context, query string, args ...any) ([]domain.AccessGrant, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grants := []domain.AccessGrant{}
+	for rows.Next() {
+		g, err := scanGrant(rows)
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, g)
+	}
+	return grants, rows.Err()
+}
+
+func scanGrant(row pgx.Row) (domain.AccessGrant, error) {
+	var g domain.AccessGrant
+	err := row.Scan(&g.ID, &g.OwnerID, &g.GranteeID, &g.ResourceType, &g.ResourceID, &g.Role, &g.CreatedAt, &g.UpdatedAt)
+	return g, err
+}
+
+func isInvalidID(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == invalidTextRepresentation
+}
@@ -0,0 +1,82 @@
+package sharing
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/pkg/api"
+)
+
+// TuyaAccountLister returns the user's linked Tuya accounts, or
+// domain.ErrAccountNotLinked when there are none.
+type TuyaAccountLister func(ctx context.Context, userID string) ([]domain.TuyaAccount, error)
+
+type DeviceLister func(ctx context.Context, tuyaUID string) ([]domain.Device, error)
+
+type Repository interface {
+	Upsert(ctx context.Context, tenantID string, grant domain.AccessGrant) (domain.AccessGrant, error)
+	ListByOwner(ctx context.Context, tenantID, ownerID string) ([]domain.AccessGrant, error)
+	ListByGrantee(ctx context.Context, tenantID, granteeID string) ([]domain.AccessGrant, error)
+	Delete(ctx context.Context, tenantID, ownerID, grantID string) error
+}
+
+type service struct {
+	repo         Repository
+	listAccounts TuyaAccountLister
+	listDevices  DeviceLister
+}
+
+func NewService(repo Repository, listAccounts TuyaAccountLister, listDevices DeviceLister) *service {
+	return &service{repo: repo, listAccounts: listAccounts, listDevices: listDevices}
+}
+
+// Grant shares a device or home owned by userID with another user. Granting
+// again to the same user and resource replaces the role.
+func (s *service) Grant(ctx context.Context, userID string, grant domain.AccessGrant) (domain.AccessGrant, error) {
+	if !domain.ValidGrantRole(grant.Role) {
+		return domain.AccessGrant{}, fmt.Errorf("%w: unknown role %q", domain.ErrInvalidGrant, grant.Role)
+	}
+	if grant.ResourceType != domain.ResourceDevice && grant.ResourceType != domain.ResourceHome {
+		return domain.AccessGrant{}, fmt.Errorf("%w: unknown resource type %q", domain.ErrInvalidGrant, grant.ResourceType)
+	}
+	if grant.GranteeID == userID {
+		return domain.AccessGrant{}, fmt.Errorf("%w: cannot share with yourself", domain.ErrInvalidGrant)
+	}
+
+	accounts, err := s.listAccounts(ctx, userID)
+	if err != nil {
+		return domain.AccessGrant{}, err
+	}
+
+	grant.OwnerID = userID
+	owned := false
+	for _, acc := range accounts {
+		devices, err := s.listDevices(api.NewContextWithRegion(ctx, acc.Region), acc.TuyaUID)
+		if err != nil {
+			return domain.AccessGrant{}, fmt.Errorf("failed to verify resource ownership: %w", err)
+		}
+		if slices.ContainsFunc(devices, grant.Covers) {
+			owned = true
+			break
+		}
+	}
+	if !owned {
+		return domain.AccessGrant{}, domain.ErrDeviceNotOwned
+	}
+
+	return s.repo.Upsert(ctx, api.GetTenantIDFromContext(ctx), grant)
+}
+
+func (s *service) ListGranted(ctx context.Context, userID string) ([]domain.AccessGrant, error) {
+	return s.repo.ListByOwner(ctx, api.GetTenantIDFromContext(ctx), userID)
+}
+
+func (s *service) ListReceived(ctx context.Context, userID string) ([]domain.AccessGrant, error) {
+	return s.repo.ListByGrantee(ctx, api.GetTenantIDFromContext(ctx), userID)
+}
+
+func (s *service) Revoke(ctx context.Context, userID string, grantID string) error {
+	return s.repo.Delete(ctx, api.GetTenantIDFromContext(ctx), userID, grantID)
+}
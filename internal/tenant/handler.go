@@ -0,0 +1,109 @@
+package tenant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/avagenc/zee-api/pkg/api"
+	"github.com/go-chi/chi/v5"
+)
+
+type Service interface {
+	Create(ctx context.Context, name string, creds domain.TuyaCredentials) (domain.Tenant, error)
+	List(ctx context.Context) ([]domain.Tenant, error)
+	UpdateCredentials(ctx context.Context, id string, creds domain.TuyaCredentials) (domain.Tenant, error)
+	Disable(ctx context.Context, id string) error
+}
+
+type Handler struct {
+	svc Service
+}
+
+func NewHandler(svc Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+type credentialsRequest struct {
+	AccessID     string `json:"access_id"`
+	AccessSecret string `json:"access_secret"`
+	BaseURL      string `json:"base_url"`
+}
+
+func (c credentialsRequest) credentials() domain.TuyaCredentials {
+	return domain.TuyaCredentials{
+		AccessID:     strings.TrimSpace(c.AccessID),
+		AccessSecret: strings.TrimSpace(c.AccessSecret),
+		BaseURL:      strings.TrimRight(strings.TrimSpace(c.BaseURL), "/"),
+	}
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	tenants, err := h.svc.List(r.Context())
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Tenants retrieved successfully", tenants, nil))
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+		credentialsRequest
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid request body", nil))
+		return
+	}
+
+	tenant, err := h.svc.Create(r.Context(), strings.TrimSpace(req.Name), req.credentials())
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusCreated, api.NewSuccessResponse("Tenant created successfully", tenant, nil))
+}
+
+func (h *Handler) UpdateCredentials(w http.ResponseWriter, r *http.Request) {
+	var req credentialsRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", "Invalid request body", nil))
+		return
+	}
+
+	tenant, err := h.svc.UpdateCredentials(r.Context(), chi.URLParam(r, "tenantId"), req.credentials())
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Tenant credentials updated successfully", tenant, nil))
+}
+
+func (h *Handler) Disable(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.Disable(r.Context(), chi.URLParam(r, "tenantId")); err != nil {
+		respondError(w, err)
+		return
+	}
+
+	api.Respond(w, http.StatusOK, api.NewSuccessResponse("Tenant disabled", nil, nil))
+}
+
+func respondError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrTenantNotFound):
+		api.Respond(w, http.StatusNotFound, api.NewErrorResponse("NOT_FOUND", "Tenant not found", nil))
+	case errors.Is(err, domain.ErrInvalidTenant):
+		api.Respond(w, http.StatusBadRequest, api.NewErrorResponse("INVALID_REQUEST", err.Error(), nil))
+	default:
+		api.Respond(w, http.StatusInternalServerError, api.NewErrorResponse("INTERNAL_ERROR", err.Error(), nil))
+	}
+}
@@ -0,0 +1,224 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/avagenc/zee-api/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const invalidTextRepresentation = "22P02"
+
+const tenantColumns = `id, name, access_id, base_url, created_at, updated_at, disabled_at`
+
+const reencryptBatchSize = 100
+
+// Cipher seals tenant access secrets at rest.
+type Cipher interface {
+	Seal(plaintext []byte) ([]byte, error)
+	Open(sealed []byte) ([]byte, error)
+	Rewrap(sealed []byte) ([]byte, bool, error)
+}
+
+type repository struct {
+	pool   *pgxpool.Pool
+	cipher Cipher
+}
+
+func NewRepository(pool *pgxpool.Pool, cipher Cipher) *repository {
+	return &repository{pool: pool, cipher: cipher}
+}
+
+func (r *repository) Create(ctx context.Context, name string, creds domain.TuyaCredentials) (domain.Tenant, error) {
+	sealed, err := r.seal(creds.AccessSecret)
+	if err != nil {
+		return domain.Tenant{}, err
+	}
+
+	query := `INSERT INTO tenants (name, access_id, access_secret, base_url) VALUES ($1, $2, $3, $4) RETURNING ` + tenantColumns
+	return scanTenant(r.pool.QueryRow(ctx, query, name, creds.AccessID, sealed, creds.BaseURL))
+}
+
+func (r *repository) List(ctx context.Context) ([]domain.Tenant, error) {
+	query := `SELECT ` + tenantColumns + ` FROM tenants ORDER BY created_at`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tenants := []domain.Tenant{}
+	for rows.Next() {
+		t, err := scanTenant(rows)
+		if err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, t)
+	}
+	return tenants, rows.Err()
+}
+
+// GetCredentials returns the decrypted Tuya credentials of an active tenant.
+func (r *repository) GetCredentials(ctx context.Context, id string) (domain.TuyaCredentials, error) {
+	var creds domain.TuyaCredentials
+	var sealed []byte
+	query := `SELECT access_id, base_url, access_secret FROM tenants WHERE id = $1 AND disabled_at IS NULL`
+
+	err := r.pool.QueryRow(ctx, query, id).Scan(&creds.AccessID, &creds.BaseURL, &sealed)
+	if err != nil {
+		if isNotFound(err) {
+			return domain.TuyaCredentials{}, domain.ErrTenantNotFound
+		}
+		return domain.TuyaCredentials{}, err
+	}
+
+	secret, err := r.cipher.Open(sealed)
+	if err != nil {
+		return domain.TuyaCredentials{}, fmt.Errorf("failed to decrypt tenant credentials: %w", err)
+	}
+	creds.AccessSecret = string(secret)
+	return creds, nil
+}
+
+func (r *repository) UpdateCredentials(ctx context.Context, id string, creds domain.TuyaCredentials) (domain.Tenant, error) {
+	sealed, err := r.seal(creds.AccessSecret)
+	if err != nil {
+		return domain.Tenant{}, err
+	}
+
+	query := `
+		UPDATE tenants SET access_id = $2, access_secret = $3, base_url = $4, updated_at = NOW()
+		WHERE id = $1 AND disabled_at IS NULL
+		RETURNING ` + tenantColumns
+
+	t, err := scanTenant(r.pool.QueryRow(ctx, query, id, creds.AccessID, sealed, creds.BaseURL))
+	if err != nil {
+		if isNotFound(err) {
+			return domain.Tenant{}, domain.ErrTenantNotFound
+		}
+		return domain.Tenant{}, err
+	}
+	return t, nil
+}
+
+func (r *repository) Disable(ctx context.Context, id string) error {
+	query := `UPDATE tenants SET disabled_at = NOW(), updated_at = NOW() WHERE id = $1 AND disabled_at IS NULL`
+
+	tag, err := r.pool.Exec(ctx, query, id)
+	if err != nil {
+		if isNotFound(err) {
+			return domain.ErrTenantNotFound
+		}
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrTenantNotFound
+	}
+	return nil
+}
+
+// Reencrypt moves every tenant secret, including those of disabled tenants,
+// to the cipher's active key and returns how many rows were rewritten.
+func (r *repository) Reencrypt(ctx context.Context) (int, error) {
+	var rewritten int
+	var lastID string
+
+	for {
+		n, last, err := r.reencryptBatch(ctx, lastID)
+		if err != nil {
+			return rewritten, err
+		}
+		rewritten += n
+		if last == "" {
+			return rewritten, nil
+		}
+		lastID = last
+	}
+}
+
+// reencryptBatch rewrites one batch of tenants after afterID and returns the
+// last ID it visited, or "" when there are no more rows.
+func (r *repository) reencryptBatch(ctx context.Context, afterID string) (int, string, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT id, access_secret FROM tenants
+		WHERE id > COALESCE(NULLIF($1, '')::uuid, '00000000-0000-0000-0000-000000000000')
+		ORDER BY id
+		LIMIT $2
+		FOR UPDATE`
+
+	rows, err := tx.Query(ctx, query, afterID, reencryptBatchSize)
+	if err != nil {
+		return 0, "", err
+	}
+
+	type row struct {
+		id     string
+		sealed []byte
+	}
+	var batch []row
+	for rows.Next() {
+		var rw row
+		if err := rows.Scan(&rw.id, &rw.sealed); err != nil {
+			rows.Close()
+			return 0, "", err
+		}
+		batch = append(batch, rw)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, "", err
+	}
+	if len(batch) == 0 {
+		return 0, "", nil
+	}
+
+	var rewritten int
+	for _, rw := range batch {
+		sealed, changed, err := r.cipher.Rewrap(rw.sealed)
+		if err != nil {
+			return 0, "", fmt.Errorf("failed to re-encrypt tenant %s: %w", rw.id, err)
+		}
+		if !changed {
+			continue
+		}
+		if _, err := tx.Exec(ctx, `UPDATE tenants SET access_secret = $2 WHERE id = $1`, rw.id, sealed); err != nil {
+			return 0, "", err
+		}
+		rewritten++
+	}
+
+	return rewritten, batch[len(batch)-1].id, tx.Commit(ctx)
+}
+
+func (r *repository) seal(secret string) ([]byte, error) {
+	sealed, err := r.cipher.Seal([]byte(secret))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt tenant credentials: %w", err)
+	}
+	return sealed, nil
+}
+
+func scanTenant(row pgx.Row) (domain.Tenant, error) {
+	var t domain.Tenant
+	err := row.Scan(&t.ID, &t.Name, &t.AccessID, &t.BaseURL, &t.CreatedAt, &t.UpdatedAt, &t.DisabledAt)
+	return t, err
+}
+
+func isNotFound(err error) bool {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == invalidTextRepresentation
+}
@@ -0,0 +1,85 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/avagenc/zee-api/internal/domain"
+)
+
+type Repository interface {
+	Create(ctx context.Context, name string, creds domain.TuyaCredentials) (domain.Tenant, error)
+	List(ctx context.Context) ([]domain.Tenant, error)
+	GetCredentials(ctx context.Context, id string) (domain.TuyaCredentials, error)
+	UpdateCredentials(ctx context.Context, id string, creds domain.TuyaCredentials) (domain.Tenant, error)
+	Disable(ctx context.Context, id string) error
+}
+
+// ClientInvalidator is notified when a tenant's credentials change so cached
+// Tuya clients are rebuilt.
+type ClientInvalidator func(tenantID string)
+
+type service struct {
+	repo       Repository
+	invalidate ClientInvalidator
+}
+
+func NewService(repo Repository, invalidate ClientInvalidator) *service {
+	return &service{repo: repo, invalidate: invalidate}
+}
+
+func (s *service) Create(ctx context.Context, name string, creds domain.TuyaCredentials) (domain.Tenant, error) {
+	if name == "" {
+		return domain.Tenant{}, fmt.Errorf("%w: name is required", domain.ErrInvalidTenant)
+	}
+	if err := validateCredentials(creds); err != nil {
+		return domain.Tenant{}, err
+	}
+
+	return s.repo.Create(ctx, name, creds)
+}
+
+func (s *service) List(ctx context.Context) ([]domain.Tenant, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *service) UpdateCredentials(ctx context.Context, id string, creds domain.TuyaCredentials) (domain.Tenant, error) {
+	if err := validateCredentials(creds); err != nil {
+		return domain.Tenant{}, err
+	}
+
+	tenant, err := s.repo.UpdateCredentials(ctx, id, creds)
+	if err != nil {
+		return domain.Tenant{}, err
+	}
+
+	s.invalidate(id)
+	return tenant, nil
+}
+
+func (s *service) Disable(ctx context.Context, id string) error {
+	if err := s.repo.Disable(ctx, id); err != nil {
+		return err
+	}
+
+	s.invalidate(id)
+	return nil
+}
+
+// Credentials returns the decrypted Tuya credentials of an active tenant.
+func (s *service) Credentials(ctx context.Context, id string) (domain.TuyaCredentials, error) {
+	return s.repo.GetCredentials(ctx, id)
+}
+
+func validateCredentials(creds domain.TuyaCredentials) error {
+	if creds.AccessID == "" || creds.AccessSecret == "" || creds.BaseURL == "" {
+		return fmt.Errorf("%w: access_id, access_secret and base_url are required", domain.ErrInvalidTenant)
+	}
+
+	u, err := url.Parse(creds.BaseURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%w: base_url must be an https URL", domain.ErrInvalidTenant)
+	}
+	return nil
+}
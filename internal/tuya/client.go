@@ -2,7 +2,9 @@ package tuya
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -31,116 +33,189 @@ type Client struct {
 	accessSecret string
 	baseURL      string
 	httpClient   *http.Client
-	token        *Token
+	store        TokenStore
+
 	tokenLock    sync.RWMutex
+	token        *Token
+	renewal      *tokenRenewal
+	refreshTimer *time.Timer
+	closed       bool
 }
 
-func NewClient(accessID, accessSecret, baseURL string) (*Client, error) {
+// NewClient builds a client that keeps its token in store, so clients sharing
+// a store share the token of their project and data center.
+func NewClient(accessID, accessSecret, baseURL string, store TokenStore) (*Client, error) {
 	client := &Client{
 		accessID:     accessID,
 		accessSecret: accessSecret,
 		baseURL:      baseURL,
 		httpClient:   &http.Client{Timeout: 10 * time.Second},
+		store:        store,
 		token:        &Token{},
-		tokenLock:    sync.RWMutex{},
 	}
 
-	if err := client.ensureValidToken(); err != nil {
+	if err := client.renewToken(context.Background(), ""); err != nil {
+		client.Close()
 		return nil, fmt.Errorf("failed to set token during client initialization: %w", err)
 	}
 
 	return client, nil
 }
 
-func (c *Client) Do(method, path string, body []byte) (json.RawMessage, error) {
-	for attempt := 0; attempt < maxIoTRequestAttempts; attempt++ {
-		fullURL := c.baseURL + path
+// Close stops the background token renewal. The client still works but
+// renews its token only when a request finds it expired.
+func (c *Client) Close() {
+	c.tokenLock.Lock()
+	defer c.tokenLock.Unlock()
 
-		var accessToken string
-		c.tokenLock.RLock()
-		if c.token != nil {
-			accessToken = c.token.AccessToken
-		}
-		c.tokenLock.RUnlock()
+	c.closed = true
+	if c.refreshTimer != nil {
+		c.refreshTimer.Stop()
+	}
+}
 
-		signature, err := generateSignature(c.accessID, c.accessSecret, accessToken, method, path, body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to generate signature: %w", err)
-		}
+type Error struct {
+	Code int
+	Msg  string
+	Tid  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("tuya api error %d: %s", e.Code, e.Msg)
+}
+
+// ErrNotSent marks failures that happened before a request reached Tuya, so
+// sending it again cannot repeat its effect.
+var ErrNotSent = errors.New("request was not sent to Tuya")
+
+type notSentError struct {
+	err error
+}
+
+func (e *notSentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *notSentError) Unwrap() []error {
+	return []error{e.err, ErrNotSent}
+}
+
+func notSent(err error) error {
+	return &notSentError{err: err}
+}
+
+func (c *Client) Do(ctx context.Context, method, path string, body []byte) (json.RawMessage, error) {
+	result, _, err := c.DoWithTID(ctx, method, path, body)
+	return result, err
+}
+
+// DoWithTID behaves like Do and also returns the Tuya transaction ID, which
+// Tuya support asks for when investigating a request.
+func (c *Client) DoWithTID(ctx context.Context, method, path string, body []byte) (json.RawMessage, string, error) {
+	req, err := ParseRequest(method, path)
+	if err != nil {
+		return nil, "", notSent(err)
+	}
+	return c.Send(ctx, req.WithBody(body))
+}
 
-		bodyReader := bytes.NewReader(body)
-		httpReq, err := http.NewRequest(method, fullURL, bodyReader)
+// Send signs and sends req, returning the result and the Tuya transaction ID.
+// Errors raised before the request went out match ErrNotSent, and Tuya's own
+// rejections are an *Error; after any other error the request may or may not
+// have taken effect.
+func (c *Client) Send(ctx context.Context, req *Request) (json.RawMessage, string, error) {
+	fullURL := c.baseURL + req.url()
+
+	for attempt := 0; attempt < maxIoTRequestAttempts; attempt++ {
+		accessToken, err := c.accessToken(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create request to %s: %w", fullURL, err)
+			return nil, "", notSent(fmt.Errorf("failed to get Tuya access token: %w", err))
 		}
 
-		if len(body) > 0 {
-			httpReq.Header.Set("Content-Type", "application/json")
+		httpReq, err := c.newHTTPRequest(ctx, req, accessToken)
+		if err != nil {
+			return nil, "", notSent(err)
 		}
-		httpReq.Header.Set("client_id", c.accessID)
-		httpReq.Header.Set("sign", signature.Sign)
-		httpReq.Header.Set("t", signature.Timestamp)
-		httpReq.Header.Set("sign_method", signature.SignMethod)
-		httpReq.Header.Set("access_token", accessToken)
-		httpReq.Header.Set("nonce", signature.Nonce)
 
 		resp, err := c.httpClient.Do(httpReq)
 		if err != nil {
-			return nil, fmt.Errorf("request to %s failed: %w", fullURL, err)
+			return nil, "", fmt.Errorf("request to %s failed: %w", fullURL, err)
 		}
 		defer resp.Body.Close()
 
 		respBodyBytes, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read response from %s: %w", fullURL, err)
+			return nil, "", fmt.Errorf("failed to read response from %s: %w", fullURL, err)
 		}
 
 		if resp.StatusCode >= 400 {
-			return nil, fmt.Errorf("request to %s returned non-200 status code: %d, body: %s", fullURL, resp.StatusCode, string(respBodyBytes))
+			return nil, "", fmt.Errorf("request to %s returned non-200 status code: %d, body: %s", fullURL, resp.StatusCode, string(respBodyBytes))
 		}
 
 		var tuyaResp response
 		if err := json.Unmarshal(respBodyBytes, &tuyaResp); err != nil {
-			return nil, fmt.Errorf("failed to decode response from %s: %w", fullURL, err)
+			return nil, "", fmt.Errorf("failed to decode response from %s: %w", fullURL, err)
 		}
 
 		if tuyaResp.Success {
-			return tuyaResp.Result, nil
+			return tuyaResp.Result, tuyaResp.Tid, nil
 		}
 
 		if tuyaResp.Code == tokenExpiredTuyaErrorCode && attempt == 0 {
-			if err := c.ensureValidToken(); err != nil {
-				return nil, fmt.Errorf("failed to refresh token after Tuya error %d: %w", tuyaResp.Code, err)
+			if err := c.renewToken(ctx, accessToken); err != nil {
+				return nil, "", notSent(fmt.Errorf("failed to refresh token after Tuya error %d: %w", tuyaResp.Code, err))
 			}
 			continue
 		}
 
-		return nil, fmt.Errorf("tuya api error %d: %s", tuyaResp.Code, tuyaResp.Msg)
+		return nil, tuyaResp.Tid, &Error{Code: tuyaResp.Code, Msg: tuyaResp.Msg, Tid: tuyaResp.Tid}
 	}
 
-	return nil, fmt.Errorf("failed to execute request to %s after retrying with a refreshed token", path)
+	return nil, "", fmt.Errorf("failed to execute request to %s after retrying with a refreshed token", req.path)
 }
 
-func (c *Client) doTokenRequest(method, path string) (*response, error) {
-	fullURL := c.baseURL + path
+// newHTTPRequest signs req with accessToken, which is empty for token
+// requests.
+func (c *Client) newHTTPRequest(ctx context.Context, req *Request, accessToken string) (*http.Request, error) {
+	fullURL := c.baseURL + req.url()
 
-	signature, err := generateSignature(c.accessID, c.accessSecret, "", method, path, nil)
+	signature, err := generateSignature(c.accessID, c.accessSecret, accessToken, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token signature: %w", err)
+		return nil, fmt.Errorf("failed to generate signature: %w", err)
 	}
 
-	httpReq, err := http.NewRequest(method, fullURL, nil)
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, fullURL, bytes.NewReader(req.body))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create token request to %s: %w", fullURL, err)
+		return nil, fmt.Errorf("failed to create request to %s: %w", fullURL, err)
 	}
 
+	if len(req.body) > 0 {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
 	httpReq.Header.Set("client_id", c.accessID)
 	httpReq.Header.Set("sign", signature.Sign)
 	httpReq.Header.Set("t", signature.Timestamp)
 	httpReq.Header.Set("sign_method", signature.SignMethod)
-	httpReq.Header.Set("access_token", "")
+	httpReq.Header.Set("access_token", accessToken)
 	httpReq.Header.Set("nonce", signature.Nonce)
 
+	if names, _ := req.signedHeaders(); names != "" {
+		httpReq.Header.Set("Signature-Headers", names)
+		for _, h := range req.headers {
+			httpReq.Header.Set(h.name, h.value)
+		}
+	}
+	return httpReq, nil
+}
+
+func (c *Client) doTokenRequest(req *Request) (*response, error) {
+	fullURL := c.baseURL + req.url()
+
+	httpReq, err := c.newHTTPRequest(context.Background(), req, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("token request to %s failed: %w", fullURL, err)
@@ -163,35 +238,3 @@ func (c *Client) doTokenRequest(method, path string) (*response, error) {
 
 	return &tuyaResp, nil
 }
-
-func (c *Client) updateToken() error {
-	resp, err := c.getToken()
-	if err != nil {
-		return fmt.Errorf("failed to get token: %w", err)
-	}
-
-	if !resp.Success {
-		return fmt.Errorf("Tuya token request failed with code %d: %s", resp.Code, resp.Msg)
-	}
-
-	var newToken Token
-	if err := json.Unmarshal(resp.Result, &newToken); err != nil {
-		return fmt.Errorf("failed to unmarshal token result: %w", err)
-	}
-
-	newToken.ExpireTime = time.Now().Unix() + newToken.ExpireTime
-
-	c.token = &newToken
-	return nil
-}
-
-func (c *Client) ensureValidToken() error {
-	c.tokenLock.Lock()
-	defer c.tokenLock.Unlock()
-
-	if c.token != nil && c.token.ExpireTime > time.Now().Unix() {
-		return nil
-	}
-
-	return c.updateToken()
-}
@@ -0,0 +1,136 @@
+package tuya
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/avagenc/zee-api/internal/domain"
+)
+
+type CredentialsLoader func(ctx context.Context, tenantID string) (domain.TuyaCredentials, error)
+
+// RouteResolver reads the tenant and region a request is bound to from its
+// context.
+type RouteResolver func(ctx context.Context) (tenantID, region string)
+
+type registryKey struct {
+	tenantID string
+	region   string
+}
+
+// Registry routes requests to the Tuya project of the tenant and the data
+// center of the region bound to the request context. Each tenant and region
+// pair gets its own Client and therefore its own token; requests with neither
+// use the deployment's default client.
+type Registry struct {
+	fallback *Client
+	load     CredentialsLoader
+	route    RouteResolver
+
+	mu      sync.Mutex
+	clients map[registryKey]*Client
+}
+
+func NewRegistry(fallback *Client, load CredentialsLoader, route RouteResolver) *Registry {
+	return &Registry{fallback: fallback, load: load, route: route, clients: make(map[registryKey]*Client)}
+}
+
+func (r *Registry) Do(ctx context.Context, method, path string, body []byte) (json.RawMessage, error) {
+	client, err := r.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(ctx, method, path, body)
+}
+
+func (r *Registry) DoWithTID(ctx context.Context, method, path string, body []byte) (json.RawMessage, string, error) {
+	client, err := r.client(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	return client.DoWithTID(ctx, method, path, body)
+}
+
+func (r *Registry) Send(ctx context.Context, req *Request) (json.RawMessage, string, error) {
+	client, err := r.client(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	return client.Send(ctx, req)
+}
+
+// Invalidate drops the cached clients of a tenant in every region so the next
+// request picks up changed or revoked credentials.
+func (r *Registry) Invalidate(tenantID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, client := range r.clients {
+		if key.tenantID == tenantID {
+			client.Close()
+			delete(r.clients, key)
+		}
+	}
+}
+
+func (r *Registry) client(ctx context.Context) (*Client, error) {
+	var key registryKey
+	key.tenantID, key.region = r.route(ctx)
+	if key == (registryKey{}) {
+		return r.fallback, nil
+	}
+
+	r.mu.Lock()
+	client, ok := r.clients[key]
+	r.mu.Unlock()
+	if ok {
+		return client, nil
+	}
+
+	// Creating a client fetches a token, so it happens outside the lock; if two
+	// requests race, the first stored client wins.
+	client, err := r.newClient(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.clients[key]; ok {
+		client.Close()
+		return existing, nil
+	}
+	r.clients[key] = client
+	return client, nil
+}
+
+func (r *Registry) newClient(ctx context.Context, key registryKey) (*Client, error) {
+	creds := domain.TuyaCredentials{
+		AccessID:     r.fallback.accessID,
+		AccessSecret: r.fallback.accessSecret,
+		BaseURL:      r.fallback.baseURL,
+	}
+
+	if key.tenantID != "" {
+		var err error
+		creds, err = r.load(ctx, key.tenantID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Tuya credentials for tenant %s: %w", key.tenantID, err)
+		}
+	}
+
+	if key.region != "" {
+		baseURL, ok := domain.TuyaRegionBaseURLs[key.region]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", domain.ErrInvalidRegion, key.region)
+		}
+		creds.BaseURL = baseURL
+	}
+
+	client, err := NewClient(creds.AccessID, creds.AccessSecret, creds.BaseURL, r.fallback.store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Tuya client for tenant %q in region %q: %w", key.tenantID, key.region, err)
+	}
+	return client, nil
+}
@@ -0,0 +1,98 @@
+package tuya
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Request is a Tuya OpenAPI call built up before it is signed and sent with
+// Client.Send. Query parameters and signed headers are kept apart from the
+// path so the string-to-sign can be canonicalised as Tuya requires.
+type Request struct {
+	method  string
+	path    string
+	query   url.Values
+	headers []header
+	body    []byte
+}
+
+type header struct {
+	name  string
+	value string
+}
+
+func NewRequest(method, path string) *Request {
+	return &Request{method: method, path: path, query: url.Values{}}
+}
+
+// ParseRequest builds a Request from a path that may carry a query string.
+func ParseRequest(method, pathAndQuery string) (*Request, error) {
+	path, rawQuery, _ := strings.Cut(pathAndQuery, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query in %s: %w", pathAndQuery, err)
+	}
+	return &Request{method: method, path: path, query: query}, nil
+}
+
+// WithQuery sets a query parameter, replacing any previous value.
+func (r *Request) WithQuery(key, value string) *Request {
+	r.query.Set(key, value)
+	return r
+}
+
+// WithSignedHeader adds a custom header that is sent and covered by the
+// signature through Signature-Headers.
+func (r *Request) WithSignedHeader(name, value string) *Request {
+	r.headers = append(r.headers, header{name: name, value: value})
+	return r
+}
+
+func (r *Request) WithBody(body []byte) *Request {
+	r.body = body
+	return r
+}
+
+// url returns the path and percent-encoded query to send.
+func (r *Request) url() string {
+	if len(r.query) == 0 {
+		return r.path
+	}
+	return r.path + "?" + r.query.Encode()
+}
+
+// canonicalURL returns the path and query as Tuya signs them: parameters
+// sorted by key and left unencoded.
+func (r *Request) canonicalURL() string {
+	if len(r.query) == 0 {
+		return r.path
+	}
+
+	keys := make([]string, 0, len(r.query))
+	for key := range r.query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range r.query[key] {
+			pairs = append(pairs, key+"="+value)
+		}
+	}
+	return r.path + "?" + strings.Join(pairs, "&")
+}
+
+// signedHeaders returns the Signature-Headers value and the header block of
+// the string-to-sign.
+func (r *Request) signedHeaders() (string, string) {
+	names := make([]string, len(r.headers))
+	var block strings.Builder
+	for i, h := range r.headers {
+		names[i] = h.name
+		block.WriteString(h.name + ":" + h.value + "\n")
+	}
+	return strings.Join(names, ":"), block.String()
+}
@@ -0,0 +1,103 @@
+package tuya
+
+import "testing"
+
+func TestRequestURLs(t *testing.T) {
+	tests := []struct {
+		name          string
+		req           *Request
+		wantURL       string
+		wantCanonical string
+	}{
+		{
+			name:          "no query",
+			req:           NewRequest("GET", "/v1.0/devices/vdevo1"),
+			wantURL:       "/v1.0/devices/vdevo1",
+			wantCanonical: "/v1.0/devices/vdevo1",
+		},
+		{
+			name:          "query sorted by key",
+			req:           NewRequest("GET", "/v1.0/devices").WithQuery("source_type", "tuyaUser").WithQuery("page_size", "50").WithQuery("page_no", "1"),
+			wantURL:       "/v1.0/devices?page_no=1&page_size=50&source_type=tuyaUser",
+			wantCanonical: "/v1.0/devices?page_no=1&page_size=50&source_type=tuyaUser",
+		},
+		{
+			name:          "values are signed unencoded",
+			req:           NewRequest("GET", "/v1.0/devices").WithQuery("device_ids", "a,b").WithQuery("name", "living room"),
+			wantURL:       "/v1.0/devices?device_ids=a%2Cb&name=living+room",
+			wantCanonical: "/v1.0/devices?device_ids=a,b&name=living room",
+		},
+		{
+			name:          "WithQuery replaces a value",
+			req:           NewRequest("GET", "/v1.0/devices").WithQuery("page_no", "1").WithQuery("page_no", "2"),
+			wantURL:       "/v1.0/devices?page_no=2",
+			wantCanonical: "/v1.0/devices?page_no=2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.req.url(); got != tt.wantURL {
+				t.Errorf("url() = %q, want %q", got, tt.wantURL)
+			}
+			if got := tt.req.canonicalURL(); got != tt.wantCanonical {
+				t.Errorf("canonicalURL() = %q, want %q", got, tt.wantCanonical)
+			}
+		})
+	}
+}
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		pathAndQuery  string
+		wantCanonical string
+		wantErr       bool
+	}{
+		{name: "path only", pathAndQuery: "/v1.0/token", wantCanonical: "/v1.0/token"},
+		{name: "query is sorted", pathAndQuery: "/v2.0/apps/schema/users?page_size=50&page_no=1", wantCanonical: "/v2.0/apps/schema/users?page_no=1&page_size=50"},
+		{name: "encoded values are decoded", pathAndQuery: "/v1.0/devices?device_ids=a%2Cb", wantCanonical: "/v1.0/devices?device_ids=a,b"},
+		{name: "invalid escape", pathAndQuery: "/v1.0/devices?device_ids=%zz", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := ParseRequest("GET", tt.pathAndQuery)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && req.canonicalURL() != tt.wantCanonical {
+				t.Errorf("canonicalURL() = %q, want %q", req.canonicalURL(), tt.wantCanonical)
+			}
+		})
+	}
+}
+
+func TestSignedHeaders(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       *Request
+		wantNames string
+		wantBlock string
+	}{
+		{name: "none", req: NewRequest("GET", "/v1.0/token")},
+		{
+			name:      "kept in the order added",
+			req:       exampleRequest("GET", "/v1.0/token"),
+			wantNames: "area_id:call_id",
+			wantBlock: "area_id:29a33e8796834b1efa6\ncall_id:8afdb70ab2ed11eb85290242ac130003\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, block := tt.req.signedHeaders()
+			if names != tt.wantNames {
+				t.Errorf("names = %q, want %q", names, tt.wantNames)
+			}
+			if block != tt.wantBlock {
+				t.Errorf("block = %q, want %q", block, tt.wantBlock)
+			}
+		})
+	}
+}
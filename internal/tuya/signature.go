@@ -18,31 +18,39 @@ type Signature struct {
 	SignMethod string `json:"sign_method"`
 }
 
-func generateSignature(accessID, accessSecret, accessToken, method, path string, body []byte) (*Signature, error) {
+// generateSignature signs req with the current time and a random nonce.
+func generateSignature(accessID, accessSecret, accessToken string, req *Request) (*Signature, error) {
 	timestamp := strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10)
 
-	hash := sha256.New()
-	hash.Write(body)
-	contentSha256 := hex.EncodeToString(hash.Sum(nil))
-
-	stringToSign := method + "\n" + contentSha256 + "\n\n" + path
-
 	nonceBytes := make([]byte, 16)
 	if _, err := rand.Read(nonceBytes); err != nil {
 		return nil, fmt.Errorf("failed to generate nonce: %w", err)
 	}
 	nonce := hex.EncodeToString(nonceBytes)
 
-	tuyaStr := accessID + accessToken + timestamp + nonce + stringToSign
-
-	mac := hmac.New(sha256.New, []byte(accessSecret))
-	mac.Write([]byte(tuyaStr))
-	sign := strings.ToUpper(hex.EncodeToString(mac.Sum(nil)))
-
 	return &Signature{
-		Sign:       sign,
+		Sign:       sign(accessID, accessSecret, accessToken, timestamp, nonce, req),
 		Timestamp:  timestamp,
 		Nonce:      nonce,
 		SignMethod: "HMAC-SHA256",
 	}, nil
 }
+
+func sign(accessID, accessSecret, accessToken, timestamp, nonce string, req *Request) string {
+	tuyaStr := accessID + accessToken + timestamp + nonce + stringToSign(req)
+
+	mac := hmac.New(sha256.New, []byte(accessSecret))
+	mac.Write([]byte(tuyaStr))
+	return strings.ToUpper(hex.EncodeToString(mac.Sum(nil)))
+}
+
+// stringToSign is the method, the body's SHA-256, one "name:value" line per
+// signed header and the canonical URL, separated by newlines.
+func stringToSign(req *Request) string {
+	hash := sha256.New()
+	hash.Write(req.body)
+	contentSha256 := hex.EncodeToString(hash.Sum(nil))
+
+	_, headerBlock := req.signedHeaders()
+	return req.method + "\n" + contentSha256 + "\n" + headerBlock + "\n" + req.canonicalURL()
+}
@@ -0,0 +1,81 @@
+package tuya
+
+import "testing"
+
+// The expected signatures are the worked examples in Tuya's signing
+// documentation.
+const (
+	exampleAccessID     = "1KAD46OrT9HafiKdsXeg"
+	exampleAccessSecret = "4OHBOnWOqaEC1mWXOpVL3yV50s0qGSRC"
+	exampleTimestamp    = "1588925778000"
+	exampleNonce        = "5138cc3a9033d69856923fd07b491173"
+	emptyBodySha256     = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+func exampleRequest(method, path string) *Request {
+	return NewRequest(method, path).
+		WithSignedHeader("area_id", "29a33e8796834b1efa6").
+		WithSignedHeader("call_id", "8afdb70ab2ed11eb85290242ac130003")
+}
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		name        string
+		accessToken string
+		req         *Request
+		want        string
+	}{
+		{
+			name: "token request",
+			req:  exampleRequest("GET", "/v1.0/token").WithQuery("grant_type", "1"),
+			want: "9E48A3E93B302EEECC803C7241985D0A34EB944F40FB573C7B5C2A82158AF13E",
+		},
+		{
+			name:        "business request with sorted query",
+			accessToken: "3f4eda2bdec17232f67c0b188af3eec1",
+			req:         exampleRequest("GET", "/v2.0/apps/schema/users").WithQuery("page_size", "50").WithQuery("page_no", "1"),
+			want:        "AE4481C692AA80B25F3A7E12C3A5FD9BBF6251539DD78E565A1A72A508A88784",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sign(exampleAccessID, exampleAccessSecret, tt.accessToken, exampleTimestamp, exampleNonce, tt.req)
+			if got != tt.want {
+				t.Errorf("sign() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringToSign(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *Request
+		want string
+	}{
+		{
+			name: "empty body without signed headers",
+			req:  NewRequest("GET", "/v1.0/token").WithQuery("grant_type", "1"),
+			want: "GET\n" + emptyBodySha256 + "\n\n/v1.0/token?grant_type=1",
+		},
+		{
+			name: "signature headers block",
+			req:  exampleRequest("GET", "/v1.0/token").WithQuery("grant_type", "1"),
+			want: "GET\n" + emptyBodySha256 + "\narea_id:29a33e8796834b1efa6\ncall_id:8afdb70ab2ed11eb85290242ac130003\n\n/v1.0/token?grant_type=1",
+		},
+		{
+			name: "body hash",
+			req:  NewRequest("POST", "/v1.0/devices/vdevo1/commands").WithBody([]byte(`{"commands":[{"code":"switch_led","value":true}]}`)),
+			want: "POST\n8479c9c60cd5d531054c49333c7b361a9ce41b9b313ab8eb6bc9df4141f658ef\n\n/v1.0/devices/vdevo1/commands",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stringToSign(tt.req); got != tt.want {
+				t.Errorf("stringToSign() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,11 +1,27 @@
 package tuya
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
+	"net/url"
+	"time"
 )
 
-const tokenEndpoint = "/v1.0/token"
+const (
+	tokenEndpoint = "/v1.0/token"
+
+	// tokenRefreshMargin is how long before expiry a token is renewed in the
+	// background, so requests never carry a token about to lapse.
+	tokenRefreshMargin = 5 * time.Minute
+	// tokenRetryDelay spaces out background renewals after a failure.
+	tokenRetryDelay = 30 * time.Second
+	// tokenStoreTimeout bounds a renewal, including the wait for another
+	// replica holding the store's lock.
+	tokenStoreTimeout = 30 * time.Second
+)
 
 type Token struct {
 	AccessToken  string `json:"access_token"`
@@ -14,7 +30,168 @@ type Token struct {
 	UID          string `json:"uid"`
 }
 
+func (t *Token) valid(now time.Time) bool {
+	return t != nil && t.AccessToken != "" && t.ExpireTime > now.Unix()
+}
+
+// tokenRenewal is an in-flight renewal that concurrent callers wait on
+// instead of starting their own.
+type tokenRenewal struct {
+	done chan struct{}
+	err  error
+}
+
 func (c *Client) getToken() (*response, error) {
-	path := fmt.Sprintf("%s?grant_type=1", tokenEndpoint)
-	return c.doTokenRequest(http.MethodGet, path)
+	return c.doTokenRequest(NewRequest(http.MethodGet, tokenEndpoint).WithQuery("grant_type", "1"))
+}
+
+func (c *Client) refreshToken(refreshToken string) (*response, error) {
+	path := fmt.Sprintf("%s/%s", tokenEndpoint, url.PathEscape(refreshToken))
+	return c.doTokenRequest(NewRequest(http.MethodGet, path))
+}
+
+// fetchToken renews current with its refresh token, falling back to a new
+// grant when there is no refresh token or Tuya rejects it.
+func (c *Client) fetchToken(current Token) (*Token, error) {
+	if current.RefreshToken != "" {
+		token, err := c.tokenFrom(c.refreshToken(current.RefreshToken))
+		if err == nil {
+			return token, nil
+		}
+		log.Printf("Warning: Tuya token refresh failed, requesting a new token: %v", err)
+	}
+	return c.tokenFrom(c.getToken())
+}
+
+// renewShared renews through the token store, adopting a token another client
+// or replica stored since this client last renewed.
+func (c *Client) renewShared(current Token, stale string) (*Token, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tokenStoreTimeout)
+	defer cancel()
+
+	return c.store.Renew(ctx, c.tokenKey(), func(stored *Token) (*Token, error) {
+		if stored != nil {
+			if stored.AccessToken != stale && stored.valid(time.Now()) {
+				return stored, nil
+			}
+			current = *stored
+		}
+		return c.fetchToken(current)
+	})
+}
+
+// tokenKey identifies the token store entry shared by clients of the same
+// project and data center.
+func (c *Client) tokenKey() string {
+	return c.accessID + "@" + c.baseURL
+}
+
+func (c *Client) tokenFrom(resp *response, err error) (*Token, error) {
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	if !resp.Success {
+		return nil, fmt.Errorf("Tuya token request failed with code %d: %s", resp.Code, resp.Msg)
+	}
+
+	var token Token
+	if err := json.Unmarshal(resp.Result, &token); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal token result: %w", err)
+	}
+
+	token.ExpireTime = time.Now().Unix() + token.ExpireTime
+	return &token, nil
+}
+
+// accessToken returns a valid access token, renewing an expired one first.
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.tokenLock.RLock()
+	token := c.token
+	c.tokenLock.RUnlock()
+
+	if token.valid(time.Now()) {
+		return token.AccessToken, nil
+	}
+
+	if err := c.renewToken(ctx, token.AccessToken); err != nil {
+		return "", err
+	}
+
+	c.tokenLock.RLock()
+	defer c.tokenLock.RUnlock()
+	return c.token.AccessToken, nil
+}
+
+// renewToken replaces the token if it is still the stale one, the access
+// token a caller found expired, rejected or due for proactive renewal. Only
+// one renewal runs at a time; concurrent callers wait for it, so a burst of
+// requests at expiry costs a single token request. The renewal itself is not
+// bound to ctx because its result is shared.
+func (c *Client) renewToken(ctx context.Context, stale string) error {
+	c.tokenLock.Lock()
+	if c.token.AccessToken != stale && c.token.valid(time.Now()) {
+		c.tokenLock.Unlock()
+		return nil
+	}
+	if renewal := c.renewal; renewal != nil {
+		c.tokenLock.Unlock()
+		return waitForRenewal(ctx, renewal)
+	}
+
+	renewal := &tokenRenewal{done: make(chan struct{})}
+	c.renewal = renewal
+	current := *c.token
+	c.tokenLock.Unlock()
+
+	token, err := c.renewShared(current, stale)
+
+	c.tokenLock.Lock()
+	if err == nil {
+		c.token = token
+		c.scheduleRefresh(token)
+	} else if !c.closed {
+		c.scheduleRenewal(stale, tokenRetryDelay)
+	}
+	c.renewal = nil
+	c.tokenLock.Unlock()
+
+	renewal.err = err
+	close(renewal.done)
+	return err
+}
+
+func waitForRenewal(ctx context.Context, renewal *tokenRenewal) error {
+	select {
+	case <-renewal.done:
+		return renewal.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// scheduleRefresh arms the background renewal of token. Callers hold
+// tokenLock.
+func (c *Client) scheduleRefresh(token *Token) {
+	if c.closed {
+		return
+	}
+
+	delay := time.Until(time.Unix(token.ExpireTime, 0).Add(-tokenRefreshMargin))
+	if delay < tokenRetryDelay {
+		delay = tokenRetryDelay
+	}
+	c.scheduleRenewal(token.AccessToken, delay)
+}
+
+// scheduleRenewal renews the token stale after delay. Callers hold tokenLock.
+func (c *Client) scheduleRenewal(stale string, delay time.Duration) {
+	if c.refreshTimer != nil {
+		c.refreshTimer.Stop()
+	}
+	c.refreshTimer = time.AfterFunc(delay, func() {
+		if err := c.renewToken(context.Background(), stale); err != nil {
+			log.Printf("Warning: background Tuya token renewal failed: %v", err)
+		}
+	})
 }
@@ -0,0 +1,58 @@
+package tuya
+
+import (
+	"context"
+	"sync"
+)
+
+// TokenRenewer receives the stored token, nil when there is none, and returns
+// the token to keep: the stored one if it is still usable, or a new one.
+type TokenRenewer func(stored *Token) (*Token, error)
+
+// TokenStore holds the token of each Tuya project and data center. Tuya may
+// revoke a project's older tokens when it issues a new one, so replicas that
+// share a store renew under its lock and adopt each other's tokens instead of
+// invalidating them.
+type TokenStore interface {
+	// Renew runs renew while holding an exclusive lock on key and stores the
+	// token it returns.
+	Renew(ctx context.Context, key string, renew TokenRenewer) (*Token, error)
+}
+
+// memoryTokenStore shares tokens between the clients of one process.
+type memoryTokenStore struct {
+	mu     sync.Mutex
+	locks  map[string]*sync.Mutex
+	tokens map[string]*Token
+}
+
+func NewMemoryTokenStore() *memoryTokenStore {
+	return &memoryTokenStore{locks: make(map[string]*sync.Mutex), tokens: make(map[string]*Token)}
+}
+
+func (s *memoryTokenStore) Renew(ctx context.Context, key string, renew TokenRenewer) (*Token, error) {
+	s.mu.Lock()
+	lock, ok := s.locks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.locks[key] = lock
+	}
+	s.mu.Unlock()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	s.mu.Lock()
+	stored := s.tokens[key]
+	s.mu.Unlock()
+
+	token, err := renew(stored)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.tokens[key] = token
+	s.mu.Unlock()
+	return token, nil
+}
@@ -0,0 +1,110 @@
+package tuyatoken
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/avagenc/zee-api/internal/tuya"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Cipher seals stored tokens when a secret key is configured.
+type Cipher interface {
+	Enabled() bool
+	Seal(plaintext []byte) ([]byte, error)
+	Open(sealed []byte) ([]byte, error)
+}
+
+type repository struct {
+	pool   *pgxpool.Pool
+	cipher Cipher
+}
+
+// NewRepository returns a tuya.TokenStore that shares tokens between replicas
+// through Postgres, serialising renewals with a row lock.
+func NewRepository(pool *pgxpool.Pool, cipher Cipher) *repository {
+	return &repository{pool: pool, cipher: cipher}
+}
+
+func (r *repository) Renew(ctx context.Context, key string, renew tuya.TokenRenewer) (*tuya.Token, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `INSERT INTO tuya_tokens (key) VALUES ($1) ON CONFLICT (key) DO NOTHING`, key); err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	var sealed bool
+	if err := tx.QueryRow(ctx, `SELECT token, sealed FROM tuya_tokens WHERE key = $1 FOR UPDATE`, key).Scan(&data, &sealed); err != nil {
+		return nil, err
+	}
+
+	stored, err := r.decode(data, sealed)
+	if err != nil {
+		// A token that cannot be read, for example after its key was removed
+		// from the keyring, is simply replaced.
+		log.Printf("Warning: discarding unreadable Tuya token %s: %v", key, err)
+		stored = nil
+	}
+
+	token, err := renew(stored)
+	if err != nil {
+		return nil, err
+	}
+
+	if token != stored {
+		encoded, encrypted, err := r.encode(token)
+		if err != nil {
+			return nil, err
+		}
+		update := `UPDATE tuya_tokens SET token = $2, sealed = $3, updated_at = NOW() WHERE key = $1`
+		if _, err := tx.Exec(ctx, update, key, encoded, encrypted); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *repository) encode(token *tuya.Token) ([]byte, bool, error) {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to marshal Tuya token: %w", err)
+	}
+	if !r.cipher.Enabled() {
+		return data, false, nil
+	}
+
+	data, err = r.cipher.Seal(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to encrypt Tuya token: %w", err)
+	}
+	return data, true, nil
+}
+
+func (r *repository) decode(data []byte, sealed bool) (*tuya.Token, error) {
+	if data == nil {
+		return nil, nil
+	}
+	if sealed {
+		var err error
+		if data, err = r.cipher.Open(data); err != nil {
+			return nil, err
+		}
+	}
+
+	var token tuya.Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
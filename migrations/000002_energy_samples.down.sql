@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_device_power_samples_device_recorded;
+DROP TABLE IF EXISTS device_power_samples;
@@ -0,0 +1,9 @@
+CREATE TABLE device_power_samples (
+    id          BIGSERIAL        PRIMARY KEY,
+    device_id   VARCHAR(255)     NOT NULL,
+    power_watts DOUBLE PRECISION NOT NULL,
+    recorded_at TIMESTAMPTZ      NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_device_power_samples_device_recorded
+    ON device_power_samples (device_id, recorded_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS device_group_members;
+DROP INDEX IF EXISTS idx_device_groups_owner_active;
+DROP TABLE IF EXISTS device_groups;
@@ -0,0 +1,18 @@
+CREATE TABLE device_groups (
+    id         UUID         PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner_id   UUID         NOT NULL,
+    name       VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_device_groups_owner_active
+    ON device_groups (owner_id)
+    WHERE deleted_at IS NULL;
+
+CREATE TABLE device_group_members (
+    group_id  UUID         NOT NULL REFERENCES device_groups (id) ON DELETE CASCADE,
+    device_id VARCHAR(255) NOT NULL,
+    PRIMARY KEY (group_id, device_id)
+);
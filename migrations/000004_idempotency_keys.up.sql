@@ -0,0 +1,14 @@
+CREATE TABLE idempotency_keys (
+    owner_id      UUID         NOT NULL,
+    key           VARCHAR(255) NOT NULL,
+    fingerprint   CHAR(64)     NOT NULL,
+    status_code   INTEGER,
+    response_body BYTEA,
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    completed_at  TIMESTAMPTZ,
+    expires_at    TIMESTAMPTZ  NOT NULL,
+    PRIMARY KEY (owner_id, key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at
+    ON idempotency_keys (expires_at);
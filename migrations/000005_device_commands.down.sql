@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_device_commands_awaiting_confirmation;
+DROP INDEX IF EXISTS idx_device_commands_queued;
+DROP TABLE IF EXISTS device_commands;
@@ -0,0 +1,24 @@
+CREATE TABLE device_commands (
+    id               UUID         PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner_id         UUID         NOT NULL,
+    device_id        VARCHAR(255) NOT NULL,
+    commands         JSONB        NOT NULL,
+    state            VARCHAR(16)  NOT NULL DEFAULT 'queued',
+    result           JSONB,
+    error            TEXT,
+    attempts         INTEGER      NOT NULL DEFAULT 0,
+    available_at     TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    confirm_deadline TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    sent_at          TIMESTAMPTZ,
+    confirmed_at     TIMESTAMPTZ
+);
+
+CREATE INDEX idx_device_commands_queued
+    ON device_commands (available_at)
+    WHERE state = 'queued';
+
+CREATE INDEX idx_device_commands_awaiting_confirmation
+    ON device_commands (confirm_deadline)
+    WHERE state = 'sent';
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS trg_command_audit_log_append_only ON command_audit_log;
+DROP FUNCTION IF EXISTS command_audit_log_append_only();
+DROP INDEX IF EXISTS idx_command_audit_log_tid;
+DROP INDEX IF EXISTS idx_command_audit_log_device_created;
+DROP INDEX IF EXISTS idx_command_audit_log_owner_created;
+DROP TABLE IF EXISTS command_audit_log;
@@ -0,0 +1,34 @@
+CREATE TABLE command_audit_log (
+    id         BIGSERIAL    PRIMARY KEY,
+    owner_id   UUID         NOT NULL,
+    tuya_uid   VARCHAR(255) NOT NULL,
+    device_id  VARCHAR(255) NOT NULL,
+    commands   JSONB        NOT NULL,
+    source     VARCHAR(16)  NOT NULL,
+    request_id VARCHAR(255),
+    success    BOOLEAN      NOT NULL,
+    result     JSONB,
+    error      TEXT,
+    tid        VARCHAR(64),
+    latency_ms BIGINT       NOT NULL,
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_command_audit_log_owner_created
+    ON command_audit_log (owner_id, created_at DESC);
+
+CREATE INDEX idx_command_audit_log_device_created
+    ON command_audit_log (device_id, created_at DESC);
+
+CREATE INDEX idx_command_audit_log_tid
+    ON command_audit_log (tid);
+
+CREATE FUNCTION command_audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'command_audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_command_audit_log_append_only
+    BEFORE UPDATE OR DELETE ON command_audit_log
+    FOR EACH ROW EXECUTE FUNCTION command_audit_log_append_only();
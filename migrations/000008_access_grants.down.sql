@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_access_grants_grantee;
+DROP TABLE IF EXISTS access_grants;
@@ -0,0 +1,14 @@
+CREATE TABLE access_grants (
+    id            UUID         PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner_id      UUID         NOT NULL,
+    grantee_id    UUID         NOT NULL,
+    resource_type VARCHAR(16)  NOT NULL,
+    resource_id   VARCHAR(255) NOT NULL,
+    role          VARCHAR(16)  NOT NULL,
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    UNIQUE (owner_id, grantee_id, resource_type, resource_id)
+);
+
+CREATE INDEX idx_access_grants_grantee
+    ON access_grants (grantee_id);
@@ -0,0 +1,21 @@
+ALTER TABLE device_commands DROP COLUMN IF EXISTS tuya_uid;
+
+DROP INDEX IF EXISTS idx_tuya_app_accounts_owner_label;
+DROP INDEX IF EXISTS idx_tuya_app_accounts_owner_default;
+DROP INDEX IF EXISTS idx_tuya_app_accounts_owner_active;
+
+DELETE FROM tuya_app_accounts
+WHERE id NOT IN (
+    SELECT DISTINCT ON (owner_id) id
+    FROM tuya_app_accounts
+    ORDER BY owner_id, deleted_at IS NULL DESC, is_default DESC, created_at
+);
+
+ALTER TABLE tuya_app_accounts DROP CONSTRAINT tuya_app_accounts_pkey;
+
+ALTER TABLE tuya_app_accounts
+    DROP COLUMN is_default,
+    DROP COLUMN label,
+    DROP COLUMN id;
+
+ALTER TABLE tuya_app_accounts ADD PRIMARY KEY (owner_id);
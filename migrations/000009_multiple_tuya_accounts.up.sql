@@ -0,0 +1,24 @@
+ALTER TABLE tuya_app_accounts DROP CONSTRAINT tuya_app_accounts_pkey;
+
+ALTER TABLE tuya_app_accounts
+    ADD COLUMN id         UUID        NOT NULL DEFAULT gen_random_uuid(),
+    ADD COLUMN label      VARCHAR(50) NOT NULL DEFAULT 'Primary',
+    ADD COLUMN is_default BOOLEAN     NOT NULL DEFAULT FALSE;
+
+ALTER TABLE tuya_app_accounts ADD PRIMARY KEY (id);
+
+UPDATE tuya_app_accounts SET is_default = TRUE WHERE deleted_at IS NULL;
+
+CREATE INDEX idx_tuya_app_accounts_owner_active
+    ON tuya_app_accounts (owner_id)
+    WHERE deleted_at IS NULL;
+
+CREATE UNIQUE INDEX idx_tuya_app_accounts_owner_default
+    ON tuya_app_accounts (owner_id)
+    WHERE is_default AND deleted_at IS NULL;
+
+CREATE UNIQUE INDEX idx_tuya_app_accounts_owner_label
+    ON tuya_app_accounts (owner_id, label)
+    WHERE deleted_at IS NULL;
+
+ALTER TABLE device_commands ADD COLUMN tuya_uid VARCHAR(255);
@@ -0,0 +1,21 @@
+DROP INDEX IF EXISTS idx_command_audit_log_tenant;
+
+ALTER TABLE command_audit_log DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE device_commands DROP COLUMN IF EXISTS tenant_id;
+
+DROP INDEX IF EXISTS idx_tuya_app_accounts_owner_default;
+DROP INDEX IF EXISTS idx_tuya_app_accounts_owner_label;
+
+ALTER TABLE tuya_app_accounts DROP COLUMN IF EXISTS tenant_id;
+
+CREATE UNIQUE INDEX idx_tuya_app_accounts_owner_default
+    ON tuya_app_accounts (owner_id)
+    WHERE is_default AND deleted_at IS NULL;
+
+CREATE UNIQUE INDEX idx_tuya_app_accounts_owner_label
+    ON tuya_app_accounts (owner_id, label)
+    WHERE deleted_at IS NULL;
+
+ALTER TABLE api_keys DROP COLUMN IF EXISTS tenant_id;
+
+DROP TABLE IF EXISTS tenants;
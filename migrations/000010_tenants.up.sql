@@ -0,0 +1,32 @@
+CREATE TABLE tenants (
+    id            UUID         PRIMARY KEY DEFAULT gen_random_uuid(),
+    name          VARCHAR(100) NOT NULL,
+    access_id     VARCHAR(255) NOT NULL,
+    access_secret BYTEA        NOT NULL,
+    base_url      VARCHAR(255) NOT NULL,
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    disabled_at   TIMESTAMPTZ
+);
+
+ALTER TABLE api_keys ADD COLUMN tenant_id UUID REFERENCES tenants (id);
+
+ALTER TABLE tuya_app_accounts ADD COLUMN tenant_id UUID REFERENCES tenants (id);
+
+DROP INDEX idx_tuya_app_accounts_owner_default;
+DROP INDEX idx_tuya_app_accounts_owner_label;
+
+CREATE UNIQUE INDEX idx_tuya_app_accounts_owner_default
+    ON tuya_app_accounts (COALESCE(tenant_id, '00000000-0000-0000-0000-000000000000'), owner_id)
+    WHERE is_default AND deleted_at IS NULL;
+
+CREATE UNIQUE INDEX idx_tuya_app_accounts_owner_label
+    ON tuya_app_accounts (COALESCE(tenant_id, '00000000-0000-0000-0000-000000000000'), owner_id, label)
+    WHERE deleted_at IS NULL;
+
+ALTER TABLE device_commands ADD COLUMN tenant_id UUID;
+
+ALTER TABLE command_audit_log ADD COLUMN tenant_id UUID;
+
+CREATE INDEX idx_command_audit_log_tenant
+    ON command_audit_log (tenant_id, id DESC);
@@ -0,0 +1,3 @@
+ALTER TABLE device_commands DROP COLUMN IF EXISTS region;
+
+ALTER TABLE tuya_app_accounts DROP COLUMN IF EXISTS region;
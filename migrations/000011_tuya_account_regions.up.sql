@@ -0,0 +1,3 @@
+ALTER TABLE tuya_app_accounts ADD COLUMN region VARCHAR(8);
+
+ALTER TABLE device_commands ADD COLUMN region VARCHAR(8);
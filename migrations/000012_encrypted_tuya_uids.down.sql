@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_tuya_app_accounts_tuya_uid_index_active;
+
+ALTER TABLE tuya_app_accounts
+    DROP COLUMN IF EXISTS tuya_uid_index,
+    DROP COLUMN IF EXISTS tuya_uid_sealed;
+
+ALTER TABLE tuya_app_accounts ALTER COLUMN tuya_uid SET NOT NULL;
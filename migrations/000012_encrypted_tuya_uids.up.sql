@@ -0,0 +1,8 @@
+ALTER TABLE tuya_app_accounts
+    ALTER COLUMN tuya_uid DROP NOT NULL,
+    ADD COLUMN tuya_uid_sealed BYTEA,
+    ADD COLUMN tuya_uid_index  VARCHAR(64);
+
+CREATE UNIQUE INDEX idx_tuya_app_accounts_tuya_uid_index_active
+    ON tuya_app_accounts (tuya_uid_index)
+    WHERE deleted_at IS NULL;
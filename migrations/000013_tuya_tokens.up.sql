@@ -0,0 +1,6 @@
+CREATE TABLE tuya_tokens (
+    key        VARCHAR(512) PRIMARY KEY,
+    token      BYTEA,
+    sealed     BOOLEAN      NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,4 @@
+UPDATE device_commands SET state = 'queued' WHERE state = 'sending';
+UPDATE device_commands SET state = 'sent' WHERE state = 'unconfirmed';
+
+DROP INDEX IF EXISTS idx_device_commands_sending;
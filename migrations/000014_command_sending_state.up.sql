@@ -0,0 +1,3 @@
+CREATE INDEX idx_device_commands_sending
+    ON device_commands (available_at)
+    WHERE state = 'sending';
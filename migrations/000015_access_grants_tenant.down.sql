@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_access_grants_grantee;
+DROP INDEX IF EXISTS idx_access_grants_owner_resource;
+
+ALTER TABLE access_grants DROP COLUMN IF EXISTS tenant_id;
+
+ALTER TABLE access_grants ADD CONSTRAINT access_grants_owner_id_grantee_id_resource_type_resource_id_key
+    UNIQUE (owner_id, grantee_id, resource_type, resource_id);
+
+CREATE INDEX idx_access_grants_grantee
+    ON access_grants (grantee_id);
@@ -0,0 +1,11 @@
+ALTER TABLE access_grants ADD COLUMN tenant_id UUID REFERENCES tenants (id);
+
+ALTER TABLE access_grants DROP CONSTRAINT access_grants_owner_id_grantee_id_resource_type_resource_id_key;
+
+CREATE UNIQUE INDEX idx_access_grants_owner_resource
+    ON access_grants (COALESCE(tenant_id, '00000000-0000-0000-0000-000000000000'), owner_id, grantee_id, resource_type, resource_id);
+
+DROP INDEX idx_access_grants_grantee;
+
+CREATE INDEX idx_access_grants_grantee
+    ON access_grants (tenant_id, grantee_id);
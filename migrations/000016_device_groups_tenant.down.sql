@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_device_groups_owner_active;
+
+ALTER TABLE device_groups DROP COLUMN IF EXISTS tenant_id;
+
+CREATE INDEX idx_device_groups_owner_active
+    ON device_groups (owner_id)
+    WHERE deleted_at IS NULL;
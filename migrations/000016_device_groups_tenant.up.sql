@@ -0,0 +1,7 @@
+ALTER TABLE device_groups ADD COLUMN tenant_id UUID REFERENCES tenants (id);
+
+DROP INDEX idx_device_groups_owner_active;
+
+CREATE INDEX idx_device_groups_owner_active
+    ON device_groups (tenant_id, owner_id)
+    WHERE deleted_at IS NULL;
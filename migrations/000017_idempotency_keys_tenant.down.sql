@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_idempotency_keys_owner_key;
+
+-- Keys are short-lived; tenant keys could collide once the tenant is dropped.
+DELETE FROM idempotency_keys WHERE tenant_id IS NOT NULL;
+
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS tenant_id;
+
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (owner_id, key);
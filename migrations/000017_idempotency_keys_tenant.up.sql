@@ -0,0 +1,6 @@
+ALTER TABLE idempotency_keys ADD COLUMN tenant_id UUID;
+
+ALTER TABLE idempotency_keys DROP CONSTRAINT idempotency_keys_pkey;
+
+CREATE UNIQUE INDEX idx_idempotency_keys_owner_key
+    ON idempotency_keys (COALESCE(tenant_id, '00000000-0000-0000-0000-000000000000'), owner_id, key);
@@ -0,0 +1 @@
+ALTER TABLE command_audit_log DROP COLUMN IF EXISTS remote_id;
@@ -0,0 +1 @@
+ALTER TABLE command_audit_log ADD COLUMN remote_id VARCHAR(255);
@@ -3,14 +3,33 @@ package api
 import (
 	"context"
 	"errors"
+	"slices"
+	"sync/atomic"
 )
 
 type contextKey int
 
 const (
 	userIDKey contextKey = iota
+	clientKey
+	tenantIDKey
+	regionKey
+	writeTrackerKey
 )
 
+// Client is the API key a request was made with. TenantID is empty for
+// platform keys, which are not bound to a tenant.
+type Client struct {
+	ID       string
+	Name     string
+	Scopes   []string
+	TenantID string
+}
+
+func (c Client) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes, scope)
+}
+
 func NewContextWithUserID(ctx context.Context, userID string) (context.Context, error) {
 	if userID == "" {
 		return nil, errors.New("user ID cannot be empty")
@@ -25,3 +44,60 @@ func GetUserIDFromContext(ctx context.Context) (string, error) {
 	}
 	return val, nil
 }
+
+func NewContextWithClient(ctx context.Context, client Client) context.Context {
+	return context.WithValue(ctx, clientKey, client)
+}
+
+func GetClientFromContext(ctx context.Context) (Client, error) {
+	val, ok := ctx.Value(clientKey).(Client)
+	if !ok || val.ID == "" {
+		return Client{}, errors.New("client not found in context")
+	}
+	return val, nil
+}
+
+// NewContextWithTenantID binds the request to a tenant. An empty tenantID
+// selects the deployment's default Tuya project.
+func NewContextWithTenantID(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantIDKey, tenantID)
+}
+
+func GetTenantIDFromContext(ctx context.Context) string {
+	val, _ := ctx.Value(tenantIDKey).(string)
+	return val
+}
+
+// NewContextWithRegion routes Tuya calls made with ctx to a regional data
+// center. An empty region uses the tenant's configured base URL.
+func NewContextWithRegion(ctx context.Context, region string) context.Context {
+	return context.WithValue(ctx, regionKey, region)
+}
+
+func GetRegionFromContext(ctx context.Context) string {
+	val, _ := ctx.Value(regionKey).(string)
+	return val
+}
+
+// WriteTracker records whether a request sent a state-changing call upstream,
+// after which a failed request may still have taken effect.
+type WriteTracker struct {
+	attempted atomic.Bool
+}
+
+func (t *WriteTracker) Attempted() bool {
+	return t.attempted.Load()
+}
+
+func NewContextWithWriteTracker(ctx context.Context) (context.Context, *WriteTracker) {
+	tracker := &WriteTracker{}
+	return context.WithValue(ctx, writeTrackerKey, tracker), tracker
+}
+
+// MarkUpstreamWrite notes on the request's tracker, if any, that a
+// state-changing call is about to be sent upstream.
+func MarkUpstreamWrite(ctx context.Context) {
+	if tracker, ok := ctx.Value(writeTrackerKey).(*WriteTracker); ok {
+		tracker.attempted.Store(true)
+	}
+}
@@ -33,6 +33,16 @@ func NewSuccessResponse(message string, data any, meta any) Response {
 	}
 }
 
+func NewPartialSuccessResponse(message string, data any, meta any) Response {
+	return Response{
+		Success: true,
+		Code:    "PARTIAL_SUCCESS",
+		Message: message,
+		Data:    data,
+		Meta:    meta,
+	}
+}
+
 func NewErrorResponse(code string, message string, errs any) Response {
 	return Response{
 		Success: false,